@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/vkuznet/goplaylist/discography"
+	"github.com/vkuznet/goplaylist/normalize"
+	"gopkg.in/yaml.v3"
+)
+
+// AliasEntry represents canonical name along with its variants and
+// preferred spellings to use in searches of music services
+type AliasEntry struct {
+	Name     string            `yaml:"name"`
+	Variants []string          `yaml:"variants,omitempty"`
+	Search   map[string]string `yaml:"search,omitempty"` // service name to spelling
+}
+
+// Aliases represents alias registry of orchestra and artist names
+type Aliases struct {
+	Orchestras []AliasEntry `yaml:"orchestras,omitempty"`
+	Artists    []AliasEntry `yaml:"artists,omitempty"`
+}
+
+// helper function to get location of alias registry file, it can be
+// changed via GOPLAYLIST_ALIASES environment
+func aliasesFile() string {
+	if fname := os.Getenv(envPrefix + "ALIASES"); fname != "" {
+		return fname
+	}
+	return filepath.Join(os.Getenv("HOME"), ".goplaylist", "aliases.yaml")
+}
+
+// helper function to read alias registry file
+func readAliases(fname string) (*Aliases, error) {
+	data, err := os.ReadFile(filepath.Clean(fname))
+	if err != nil {
+		return nil, err
+	}
+	var aliases Aliases
+	if err := yaml.Unmarshal(data, &aliases); err != nil {
+		return nil, fmt.Errorf("unable to parse aliases file %s: %w", fname, err)
+	}
+	return &aliases, nil
+}
+
+// helper function to register aliases in given resolver
+func (a *Aliases) register(r *normalize.Resolver) {
+	for _, entry := range a.Orchestras {
+		r.AddOrchestra(entry.Name, entry.Variants...)
+		for service, spelling := range entry.Search {
+			r.AddSearch(entry.Name, service, spelling)
+		}
+	}
+	for _, entry := range a.Artists {
+		r.AddArtist(entry.Name, entry.Variants...)
+		for service, spelling := range entry.Search {
+			r.AddSearch(entry.Name, service, spelling)
+		}
+	}
+}
+
+// helper function to load alias registry file into default resolver, it
+// is not an error if the file does not exist
+func loadAliases(fname string) error {
+	aliases, err := readAliases(fname)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	aliases.register(normalize.DefaultResolver)
+	return nil
+}
+
+// NameVariants represents spellings of unknown name found in discographies
+type NameVariants struct {
+	Kind      string         // orchestra or artist
+	Spellings map[string]int // spelling of the name to number of tracks
+	Tracks    int            // total number of tracks
+}
+
+// helper function to get the most used spelling of the name
+func (n NameVariants) name() string {
+	var best string
+	for spelling, count := range n.Spellings {
+		if best == "" || count > n.Spellings[best] || (count == n.Spellings[best] && spelling < best) {
+			best = spelling
+		}
+	}
+	return best
+}
+
+// helper function to get all spellings of the name except the most used one
+func (n NameVariants) variants() []string {
+	name := n.name()
+	var variants []string
+	for spelling := range n.Spellings {
+		if spelling != name {
+			variants = append(variants, spelling)
+		}
+	}
+	sort.Strings(variants)
+	return variants
+}
+
+// helper function to collect orchestra and artist names of discography
+// which are not known to alias registry, spellings of the same name are
+// grouped together
+func unknownNames(discography *Discography) []NameVariants {
+	groups := make(map[string]*NameVariants)
+	add := func(kind, key, spelling string) {
+		gkey := kind + "|" + key
+		group, ok := groups[gkey]
+		if !ok {
+			group = &NameVariants{Kind: kind, Spellings: make(map[string]int)}
+			groups[gkey] = group
+		}
+		group.Spellings[spelling]++
+		group.Tracks++
+	}
+	for _, track := range discography.Tracks {
+		orchestra := track.Orchestra
+		if orchestra == "" {
+			orchestra = discography.Orchestra
+		}
+		if orchestra != "" && !normalize.DefaultResolver.KnownOrchestra(orchestra) {
+			add("orchestra", normalize.OrchestraKey(orchestra), orchestra)
+		}
+		for _, names := range []string{track.Vocal, track.Artist} {
+			for _, name := range strings.Split(names, ",") {
+				name = strings.TrimSpace(name)
+				if name != "" && !normalize.DefaultResolver.KnownArtist(name) {
+					add("artist", normalize.Key(name), name)
+				}
+			}
+		}
+	}
+	var result []NameVariants
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+	// names with many spellings come first as they are likely aliases
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i].Spellings) != len(result[j].Spellings) {
+			return len(result[i].Spellings) > len(result[j].Spellings)
+		}
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind > result[j].Kind
+		}
+		return result[i].name() < result[j].name()
+	})
+	return result
+}
+
+// aliasesCommand lists orchestra and artist names of discographies which
+// are not known to alias registry
+func aliasesCommand(args []string) error {
+	var opts Options
+	var format string
+	fs := newFlagSet("aliases", "List orchestra and artist name variants which are not known to alias registry "+aliasesFile()+".")
+	fs.StringVar(&opts.File, "file", "", "xml or csv file to read, may be a glob pattern")
+	fs.StringVar(&format, "format", "text", "output format: text or yaml (alias registry entries)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if opts.File == "" {
+		return errors.New("no input file is provided, please use -file option")
+	}
+	// read raw discography to see all variants of names
+	disc, err := discography.ReadFile(opts.File)
+	if err != nil {
+		return err
+	}
+	names := unknownNames(disc)
+	if format == "yaml" {
+		var aliases Aliases
+		for _, n := range names {
+			entry := AliasEntry{Name: n.name(), Variants: n.variants()}
+			if n.Kind == "orchestra" {
+				aliases.Orchestras = append(aliases.Orchestras, entry)
+			} else {
+				aliases.Artists = append(aliases.Artists, entry)
+			}
+		}
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		defer enc.Close()
+		return enc.Encode(aliases)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tTRACKS\tNAME\tVARIANTS")
+	for _, n := range names {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", n.Kind, n.Tracks, n.name(), strings.Join(n.variants(), " | "))
+	}
+	return w.Flush()
+}
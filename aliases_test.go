@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vkuznet/goplaylist/normalize"
+)
+
+// helper function to load aliases into fresh default resolver which is
+// restored when test is finished
+func setupAliases(t *testing.T, content string) {
+	t.Helper()
+	resolver := normalize.DefaultResolver
+	normalize.DefaultResolver = normalize.NewResolver(nil)
+	t.Cleanup(func() { normalize.DefaultResolver = resolver })
+
+	fname := filepath.Join(t.TempDir(), "aliases.yaml")
+	if err := os.WriteFile(fname, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadAliases(fname); err != nil {
+		t.Fatalf("unable to load aliases: %v", err)
+	}
+}
+
+func TestLoadAliases(t *testing.T) {
+	setupAliases(t, `
+orchestras:
+  - name: Juan D'Arienzo
+    variants: ["D'Arienzo", "Juan Darienzo"]
+    search:
+      spotify: Juan D'Arienzo y su Orquesta Típica
+artists:
+  - name: Alberto Echagüe
+    variants: ["Echague"]
+`)
+	// missing file is not an error
+	if err := loadAliases(filepath.Join(t.TempDir(), "missing.yaml")); err != nil {
+		t.Errorf("missing aliases file should be ignored, got %v", err)
+	}
+
+	fname := filepath.Join(t.TempDir(), "test.csv")
+	data := "Juan Darienzo,1938,Nada más,,Tango,Echague\nD'Arienzo,1937,Pensalo bien\n"
+	if err := os.WriteFile(fname, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	discography, err := readFile(fname, "", "", map[string]string{"vocal": "Alberto Echague"})
+	if err != nil {
+		t.Fatalf("readFile failed: %v", err)
+	}
+	if len(discography.Tracks) != 1 {
+		t.Fatalf("expected 1 track with vocal filter, got %d", len(discography.Tracks))
+	}
+	track := discography.Tracks[0]
+	if track.Orchestra != "Juan D'Arienzo" || track.Vocal != "Alberto Echagüe" {
+		t.Errorf("aliases are not resolved: %+v", track)
+	}
+
+	if query := (&SpotifyProvider{}).Query(track); query != "track:Nada más artist:Juan D'Arienzo y su Orquesta Típica" {
+		t.Errorf("unexpected spotify query: %s", query)
+	}
+	if query := (&YoutubeProvider{}).Query(track); query != "Nada más Juan D'Arienzo 1938" {
+		t.Errorf("unexpected youtube query: %s", query)
+	}
+}
+
+func TestUnknownNames(t *testing.T) {
+	setupAliases(t, `
+orchestras:
+  - name: Ricardo Tanturi
+`)
+	discography := &Discography{
+		Tracks: []Track{
+			{Name: "Una noche más", Orchestra: "Ricardo Tanturi", Year: "1941"},
+			{Name: "Nada más", Orchestra: "Juan D'Arienzo", Vocal: "Alberto Echagüe", Year: "1938"},
+			{Name: "El flete", Orchestra: "Juan D'arienzo", Year: "1936"},
+			{Name: "La cumparsita", Orchestra: "Juan D'Arienzo", Year: "1937"},
+		},
+	}
+	names := unknownNames(discography)
+	if len(names) != 2 {
+		t.Fatalf("expected 2 unknown names, got %+v", names)
+	}
+	first := names[0]
+	if first.Kind != "orchestra" || first.name() != "Juan D'Arienzo" || first.Tracks != 3 {
+		t.Errorf("unexpected unknown orchestra: %+v", first)
+	}
+	if variants := first.variants(); len(variants) != 1 || variants[0] != "Juan D'arienzo" {
+		t.Errorf("unexpected variants: %v", variants)
+	}
+	if names[1].Kind != "artist" || names[1].name() != "Alberto Echagüe" {
+		t.Errorf("unexpected unknown artist: %+v", names[1])
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Job represents specification of a playlist built by batch command or
+// submitted to API server
+type Job struct {
+	Title       string            `yaml:"title" json:"title,omitempty"`             // title of the playlist, default is taken from file name
+	File        string            `yaml:"file" json:"file,omitempty"`               // xml or csv file to read, may be a glob pattern
+	FilterBy    map[string]string `yaml:"filterBy" json:"filterBy,omitempty"`       // filter tracks conditions
+	SortBy      string            `yaml:"sortBy" json:"sortBy,omitempty"`           // sort tracks by attribute
+	SortOrder   string            `yaml:"sortOrder" json:"sortOrder,omitempty"`     // sort order: ascending or descending
+	Service     string            `yaml:"service" json:"service,omitempty"`         // service to use, default is taken from configuration
+	Privacy     string            `yaml:"privacy" json:"privacy,omitempty"`         // playlist privacy, default is taken from configuration
+	Description string            `yaml:"description" json:"description,omitempty"` // playlist description template
+	Cover       string            `yaml:"cover" json:"cover,omitempty"`             // playlist cover image
+	SplitBy     string            `yaml:"splitBy" json:"splitBy,omitempty"`         // split tracks into playlists by attribute
+	Dedupe      string            `yaml:"dedupe" json:"dedupe,omitempty"`           // duplicate tracks policy: exact, recording, title or none, default is exact
+}
+
+// JobFile represents batch job file, it can be written either in YAML or JSON
+type JobFile struct {
+	Quota map[string]int `yaml:"quota"` // number of service searches allowed per session
+	Jobs  []Job          `yaml:"jobs"`
+}
+
+// JobResult represents outcome of the batch job
+type JobResult struct {
+	Index   int // number of the job in job file
+	Job     Job
+	Service string
+	BuildResult
+	Err error
+}
+
+// helper function to read batch job file, since JSON is a subset of YAML
+// the same parser is used for both formats
+func readJobFile(fname string) (*JobFile, error) {
+	data, err := os.ReadFile(filepath.Clean(fname))
+	if err != nil {
+		return nil, err
+	}
+	var jobFile JobFile
+	if err := yaml.Unmarshal(data, &jobFile); err != nil {
+		return nil, fmt.Errorf("unable to parse job file %s: %w", fname, err)
+	}
+	if len(jobFile.Jobs) == 0 {
+		return nil, fmt.Errorf("no jobs found in %s", fname)
+	}
+	for idx, job := range jobFile.Jobs {
+		if job.File == "" {
+			return nil, fmt.Errorf("job %d has no file", idx+1)
+		}
+	}
+	return &jobFile, nil
+}
+
+// helper function to get command line options of the job, playlist
+// parameters are applied via configuration, see config method
+func (j Job) options() Options {
+	opts := Options{
+		File:      j.File,
+		Title:     j.Title,
+		SortBy:    j.SortBy,
+		SortOrder: j.SortOrder,
+		Service:   j.Service,
+		SplitBy:   j.SplitBy,
+		Dedupe:    j.Dedupe,
+	}
+	if opts.SortOrder == "" {
+		opts.SortOrder = "ascending"
+	}
+	if opts.Dedupe == "" {
+		opts.Dedupe = "exact"
+	}
+	return opts
+}
+
+// helper function to apply job parameters on top of given configuration
+func (j Job) config(base Configuration) Configuration {
+	config := base
+	// playlist title of configuration is not applied to jobs
+	config.PlaylistTitle = ""
+	if j.Privacy != "" {
+		config.Privacy = j.Privacy
+	}
+	if j.Description != "" {
+		config.Description = j.Description
+	}
+	if j.Cover != "" {
+		config.Cover = j.Cover
+	}
+	return config
+}
+
+// helper function to run batch jobs, the jobs are executed sequentially
+// and every service is authorized only once, all jobs of the service share
+// its session, local cache and quota of searches, a job which splits its
+// discography produces one result per playlist
+func runBatch(jobFile *JobFile) []JobResult {
+	base := Config
+	defer func() { Config = base }()
+
+	// read discographies of all jobs, validate them and collect session
+	// options before we start any OAuth flow
+	var results []JobResult
+	var discographies []*Discography
+	sessionOpts := make(map[string]*SessionOptions)
+	for idx, job := range jobFile.Jobs {
+		Config = job.config(base)
+		opts := job.options()
+		service := opts.serviceName()
+		failed := func(err error) {
+			results = append(results, JobResult{Index: idx + 1, Job: job, Service: service, Err: err})
+			discographies = append(discographies, nil)
+		}
+		if err := Config.Validate(service); err != nil {
+			failed(err)
+			continue
+		}
+		discography, err := opts.loadDiscography(job.FilterBy)
+		if err != nil {
+			failed(fmt.Errorf("error reading discography file: %w", err))
+			continue
+		}
+		specs, err := opts.playlists(discography)
+		if err != nil {
+			failed(err)
+			continue
+		}
+		for _, spec := range specs {
+			result := JobResult{Index: idx + 1, Job: job, Service: service}
+			result.Title = spec.Title
+			results = append(results, result)
+			discographies = append(discographies, spec.Discography)
+		}
+		sopts, ok := sessionOpts[service]
+		if !ok {
+			sopts = &SessionOptions{}
+			if limit := jobFile.Quota[service]; limit > 0 {
+				sopts.Quota = &Quota{Limit: limit}
+			}
+			sessionOpts[service] = sopts
+		}
+		sopts.Privacies = append(sopts.Privacies, playlistPrivacy())
+		sopts.Cover = sopts.Cover || Config.Cover != ""
+	}
+
+	sessions := make(map[string]Session)
+	sessionErrors := make(map[string]error)
+	defer func() {
+		for _, session := range sessions {
+			session.Close()
+		}
+	}()
+	for idx := range results {
+		result := &results[idx]
+		if result.Err != nil {
+			continue
+		}
+		Config = result.Job.config(base)
+		fmt.Printf("job %d: creating %s playlist: %s\n", result.Index, result.Service, result.Title)
+
+		session, ok := sessions[result.Service]
+		if !ok {
+			if err, failed := sessionErrors[result.Service]; failed {
+				result.Err = err
+				continue
+			}
+			var err error
+			session, err = openSession(result.Service, *sessionOpts[result.Service])
+			if err != nil {
+				sessionErrors[result.Service] = err
+				result.Err = err
+				continue
+			}
+			sessions[result.Service] = session
+		}
+		if sessionOpts[result.Service].Quota.Exhausted() {
+			result.Err = errQuotaExhausted
+			continue
+		}
+		popts := Config.playlistOptions()
+		popts.ReplaceCover = result.Job.Cover != ""
+		build, err := session.Build(result.Title, discographies[idx], popts)
+		result.BuildResult = build
+		result.Err = err
+		if err != nil {
+			log.Printf("job %d: couldn't build playlist: %v", result.Index, err)
+		}
+	}
+	return results
+}
+
+// helper function to print summary of batch jobs
+func printJobResults(results []JobResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "JOB\tSERVICE\tPLAYLIST\tADDED\tEXISTING\tNOT FOUND\tLOW CONFIDENCE\tNEVER\tFAILED\tSTATUS")
+	for _, r := range results {
+		status := "ok"
+		if r.Err != nil {
+			status = "error: " + strings.ReplaceAll(r.Err.Error(), "\n", "; ")
+		} else if r.URL != "" {
+			status = r.URL
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%s\n",
+			r.Index, r.Service, r.Title, r.Added, r.Existing, r.NotFound, r.LowConfidence, r.Skipped, r.Failed, status)
+	}
+	w.Flush()
+}
+
+// batchCommand builds many playlists described in the job file
+func batchCommand(args []string) error {
+	var opts Options
+	var jobs string
+	fs := newFlagSet("batch", "Build playlists described in YAML or JSON job file within one session per service.")
+	fs.StringVar(&opts.Config, "config", "", "configuration file")
+	fs.StringVar(&opts.Profile, "profile", "", "configuration profile to use")
+	fs.StringVar(&jobs, "jobs", "", "YAML or JSON job file")
+	fs.StringVar(&opts.Report, "report", "", "write outcome of every track to json or csv report file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if jobs == "" {
+		return errors.New("no job file is provided, please use -jobs option")
+	}
+	if opts.Report != "" {
+		if _, err := reportFormat(opts.Report); err != nil {
+			return err
+		}
+	}
+	if err := opts.loadConfig(true); err != nil {
+		return err
+	}
+	jobFile, err := readJobFile(jobs)
+	if err != nil {
+		return err
+	}
+	run := RunReport{Started: time.Now()}
+	results := runBatch(jobFile)
+	run.Finished = time.Now()
+	printJobResults(results)
+	if opts.Report != "" {
+		for _, r := range results {
+			playlist := PlaylistReport{Service: r.Service, BuildResult: r.BuildResult}
+			if r.Err != nil {
+				playlist.Error = r.Err.Error()
+			}
+			run.Playlists = append(run.Playlists, playlist)
+		}
+		if err := writeReport(opts.Report, run); err != nil {
+			return fmt.Errorf("unable to write report %s: %w", opts.Report, err)
+		}
+		fmt.Println("report of the run is written to", opts.Report)
+	}
+	var failed int
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d playlists failed", failed, len(results))
+	}
+	return nil
+}
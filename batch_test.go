@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	musicDir := t.TempDir()
+	outputDir := t.TempDir()
+	os.WriteFile(filepath.Join(musicDir, "Sin rumbo fijo.mp3"), make([]byte, 128), 0644)
+
+	fname := filepath.Join(t.TempDir(), "jobs.yaml")
+	content := `
+quota:
+  youtube: 50
+jobs:
+  - title: Valses
+    file: testplaylist.xml
+    filterBy: {genre: vals}
+    service: local
+  - title: Tangos
+    file: testplaylist.xml
+    filterBy: {genre: tango}
+    sortBy: year
+    service: local
+  - title: Spotify tangos
+    file: testplaylist.xml
+    service: spotify
+    privacy: hidden
+`
+	if err := os.WriteFile(fname, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	jobFile, err := readJobFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobFile.Jobs) != 3 || jobFile.Quota["youtube"] != 50 || jobFile.Jobs[0].FilterBy["genre"] != "vals" {
+		t.Fatalf("wrong job file %+v", jobFile)
+	}
+	if opts := jobFile.Jobs[0].options(); opts.Dedupe != "exact" {
+		t.Errorf("wrong default dedupe policy %q", opts.Dedupe)
+	}
+
+	Config = Configuration{MusicDir: musicDir, OutputDir: outputDir, Privacy: "private"}
+	defer func() { Config = Configuration{} }()
+	results := runBatch(jobFile)
+	if Config.Privacy != "private" {
+		t.Errorf("configuration is not restored after batch %+v", Config)
+	}
+
+	valses, tangos, spotify := results[0], results[1], results[2]
+	if valses.Err != nil || valses.Added != 1 || valses.NotFound != 1 {
+		t.Errorf("wrong result of valses job %+v", valses)
+	}
+	if tangos.Err != nil || tangos.Added != 0 || tangos.NotFound != 8 {
+		t.Errorf("wrong result of tangos job %+v", tangos)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "Tangos.m3u8")); err != nil {
+		t.Errorf("playlist of tangos job is not written: %v", err)
+	}
+	// spotify job should fail validation before any authorization attempt
+	if spotify.Err == nil || !strings.Contains(spotify.Err.Error(), "invalid privacy") {
+		t.Errorf("expected validation error of spotify job, got %v", spotify.Err)
+	}
+}
+
+func TestQuota(t *testing.T) {
+	var unlimited *Quota
+	if err := unlimited.Spend(); err != nil || unlimited.Exhausted() {
+		t.Error("nil quota should be unlimited")
+	}
+	quota := &Quota{Limit: 2}
+	for i := 0; i < 2; i++ {
+		if err := quota.Spend(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := quota.Spend(); err != errQuotaExhausted || !quota.Exhausted() {
+		t.Errorf("expected exhausted quota, got %v", err)
+	}
+}
@@ -6,7 +6,11 @@ import (
 	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+
+	"github.com/vkuznet/goplaylist/discography"
 )
 
 // Cache represents local cache object
@@ -15,6 +19,18 @@ type Cache struct {
 	Tracks []Track
 }
 
+// CachedPlaylist represents playlist entry of local cache
+type CachedPlaylist struct {
+	Title  string
+	ID     string
+	Tracks int
+}
+
+// helper function to construct cache directory of given service
+func cacheDir(service string) string {
+	return fmt.Sprintf("%s/.goplaylist/%s", os.Getenv("HOME"), service)
+}
+
 // Init method initialize cache
 func (c *Cache) Init(service, dir string) {
 	c.Dir = dir
@@ -35,9 +51,39 @@ func (c *Cache) Init(service, dir string) {
 	}
 }
 
+// helper function to get name of cache directory of playlist title or ID,
+// path separators are replaced, therefore the directory stays within cache
+func cacheName(name string) string {
+	name = strings.NewReplacer("/", "_", `\`, "_").Replace(name)
+	if name == "." || name == ".." {
+		return strings.Repeat("_", len(name))
+	}
+	return name
+}
+
+// helper function to get cache directory of playlists with given title
+func (c *Cache) playlistDir(title string) string {
+	return filepath.Join(c.Dir, cacheName(title))
+}
+
+// Remove removes cache of playlists with given title
+func (c *Cache) Remove(title string) error {
+	if cacheName(title) == "" {
+		return fmt.Errorf("empty playlist title")
+	}
+	pdir := c.playlistDir(title)
+	if rel, err := filepath.Rel(c.Dir, pdir); err != nil || rel != filepath.Base(pdir) {
+		return fmt.Errorf("invalid playlist title '%s'", title)
+	}
+	if _, err := os.Stat(pdir); err != nil {
+		return fmt.Errorf("no cache of playlist '%s': %w", title, err)
+	}
+	return os.RemoveAll(pdir)
+}
+
 // helper function to construct cache file
 func (c *Cache) cacheFile(title, playlistID string) (string, error) {
-	cdir := fmt.Sprintf("%s/%s/%s", c.Dir, title, playlistID)
+	cdir := filepath.Join(c.playlistDir(title), cacheName(playlistID))
 	err := os.MkdirAll(cdir, os.ModePerm)
 	if err != nil {
 		return "", err
@@ -110,10 +156,11 @@ func (c *Cache) CheckTrack(title, playlistID string, track Track) bool {
 	defer file.Close()
 
 	// Perform a line-by-line scan
+	key := track.Key()
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		line := scanner.Text()
-		if line == track.String() {
+		cached := discography.ParseTrack(scanner.Text())
+		if cached.Key() == key {
 			return true
 		}
 	}
@@ -147,9 +194,136 @@ func (c *Cache) Load(service, title, playlistID string) ([]Track, error) {
 	}
 	for _, t := range strings.Split(string(data), "\n") {
 		if t != "" {
-			track := constructTrack(t)
+			track := discography.ParseTrack(t)
 			tracks = append(tracks, track)
 		}
 	}
 	return tracks, nil
 }
+
+// helper function to construct cache file with resolved service IDs of tracks
+func (c *Cache) idsFile(title, playlistID string) (string, error) {
+	cacheFile, err := c.cacheFile(title, playlistID)
+	if err != nil {
+		return "", err
+	}
+	return strings.Replace(cacheFile, "cache.txt", "ids.txt", 1), nil
+}
+
+// AddID adds resolved service ID of the track (e.g. Spotify track ID or MPD
+// file URI) to the cache
+func (c *Cache) AddID(title, playlistID string, track Track, id string) error {
+	idsFile, err := c.idsFile(title, playlistID)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(idsFile, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening cache file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(track.String() + "\t" + id + "\n"); err != nil {
+		return fmt.Errorf("error writing to cache file: %w", err)
+	}
+	return nil
+}
+
+// LoadIDs loads resolved service IDs of tracks from the cache, it returns
+// map of track string representation to its service ID
+func (c *Cache) LoadIDs(title, playlistID string) (map[string]string, error) {
+	ids := make(map[string]string)
+	idsFile, err := c.idsFile(title, playlistID)
+	if err != nil {
+		return ids, err
+	}
+	data, err := os.ReadFile(idsFile)
+	if os.IsNotExist(err) {
+		return ids, nil
+	} else if err != nil {
+		return ids, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if arr := strings.SplitN(line, "\t", 2); len(arr) == 2 {
+			ids[arr[0]] = arr[1]
+		}
+	}
+	return ids, nil
+}
+
+// TrackStatus reports status of playlist tracks in local cache of playlists
+// with given title: "cached" if track was already added to the playlist,
+// "resolved" if its service ID is known and "new" if the track should be
+// searched in the service
+func (c *Cache) TrackStatus(service, title string, tracks []Track) ([]string, error) {
+	cached := make(map[string]bool)
+	resolved := make(map[string]bool)
+	entries, err := os.ReadDir(c.playlistDir(title))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		ctracks, err := c.Load(service, title, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		for _, track := range ctracks {
+			cached[track.Key()] = true
+		}
+		ids, err := c.LoadIDs(title, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		for trk := range ids {
+			track := discography.ParseTrack(trk)
+			resolved[track.Key()] = true
+		}
+	}
+	var status []string
+	for _, track := range tracks {
+		key := track.Key()
+		if cached[key] {
+			status = append(status, "cached")
+		} else if resolved[key] || track.ServiceID(service) != "" {
+			status = append(status, "resolved")
+		} else {
+			status = append(status, "new")
+		}
+	}
+	return status, nil
+}
+
+// Playlists returns list of playlists found in local cache along with
+// number of their cached tracks
+func (c *Cache) Playlists() ([]CachedPlaylist, error) {
+	var playlists []CachedPlaylist
+	files, err := filepath.Glob(filepath.Join(c.Dir, "*", "*", "cache.txt"))
+	if err != nil {
+		return playlists, err
+	}
+	sort.Strings(files)
+	for _, fname := range files {
+		pdir := filepath.Dir(fname)
+		playlist := CachedPlaylist{
+			Title: filepath.Base(filepath.Dir(pdir)),
+			ID:    filepath.Base(pdir),
+		}
+		tracks, err := c.Load("", playlist.Title, playlist.ID)
+		if err != nil {
+			return playlists, err
+		}
+		playlist.Tracks = len(tracks)
+		if playlist.Tracks == 0 {
+			// some services (e.g. mpd) only keep resolved IDs of tracks
+			ids, err := c.LoadIDs(playlist.Title, playlist.ID)
+			if err != nil {
+				return playlists, err
+			}
+			playlist.Tracks = len(ids)
+		}
+		playlists = append(playlists, playlist)
+	}
+	return playlists, nil
+}
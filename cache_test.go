@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -93,3 +95,89 @@ func TestCacheLoad(t *testing.T) {
 		}
 	}
 }
+
+func TestCacheIDs(t *testing.T) {
+	cache := Cache{}
+	cache.Init("mpd", t.TempDir())
+
+	title := "MyPlaylist"
+	track := Track{Name: "Name", Year: "1941", Orchestra: "Orchestra"}
+	ids, err := cache.LoadIDs(title, title)
+	if err != nil || len(ids) != 0 {
+		t.Fatalf("expected empty ids cache, got %v, error %v", ids, err)
+	}
+	if err := cache.AddID(title, title, track, "Tango/Orchestra/Name.mp3"); err != nil {
+		t.Fatal(err)
+	}
+	ids, err = cache.LoadIDs(title, title)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids[track.String()] != "Tango/Orchestra/Name.mp3" {
+		t.Fatalf("wrong id for track %s: %v", track.String(), ids)
+	}
+}
+
+func TestCacheTrackStatus(t *testing.T) {
+	cache := Cache{}
+	cache.Init("spotify", t.TempDir())
+
+	title := "MyPlaylist"
+	added := Track{Name: "Added", Year: "1941", Orchestra: "Orchestra"}
+	resolved := Track{Name: "Resolved", Year: "1941", Orchestra: "Orchestra"}
+	known := Track{Name: "Known", Year: "1941", Orchestra: "Orchestra", SpotifyID: "abc"}
+	other := Track{Name: "Other", Year: "1941", Orchestra: "Orchestra"}
+	if err := cache.AddTrack(title, "12345", added); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.AddID(title, "12345", resolved, "def"); err != nil {
+		t.Fatal(err)
+	}
+	status, err := cache.TrackStatus("spotify", title, []Track{added, resolved, known, other})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"cached", "resolved", "resolved", "new"}
+	if strings.Join(status, ",") != strings.Join(expect, ",") {
+		t.Errorf("expected status %v, got %v", expect, status)
+	}
+
+	// tracks of unknown playlists are new
+	status, err = cache.TrackStatus("spotify", "Unknown", []Track{added})
+	if err != nil || len(status) != 1 || status[0] != "new" {
+		t.Errorf("unexpected status of unknown playlist %v, error %v", status, err)
+	}
+}
+
+func TestCacheRemove(t *testing.T) {
+	dir := t.TempDir()
+	outside := filepath.Join(dir, "outside")
+	os.MkdirAll(outside, 0755)
+	cache := Cache{}
+	cache.Init("mpd", filepath.Join(dir, "mpd"))
+
+	// titles with path separators stay within the cache
+	title := "Tangos/Valses"
+	track := Track{Name: "Name", Year: "1941", Orchestra: "Orchestra"}
+	if err := cache.AddID(title, title, track, "Name.mp3"); err != nil {
+		t.Fatal(err)
+	}
+	playlists, err := cache.Playlists()
+	if err != nil || len(playlists) != 1 || playlists[0].Title != "Tangos_Valses" || playlists[0].Tracks != 1 {
+		t.Fatalf("unexpected cached playlists %+v, error %v", playlists, err)
+	}
+	for _, bad := range []string{"", "..", "../outside"} {
+		if err := cache.Remove(bad); err == nil {
+			t.Errorf("cache of title '%s' should not be removed", bad)
+		}
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("directory outside of cache is removed: %v", err)
+	}
+	if err := cache.Remove(title); err != nil {
+		t.Fatal(err)
+	}
+	if playlists, _ := cache.Playlists(); len(playlists) != 0 {
+		t.Errorf("cache of playlist is not removed %+v", playlists)
+	}
+}
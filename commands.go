@@ -0,0 +1,687 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vkuznet/goplaylist/discography"
+)
+
+// Command represents goplaylist sub-command
+type Command struct {
+	Name        string                    // name of the command
+	Description string                    // one line description shown in usage
+	Run         func(args []string) error // function which runs the command
+}
+
+// commands holds list of supported sub-commands
+var commands []Command
+
+func init() {
+	commands = []Command{
+		{Name: "tracks", Description: "show discography tracks and exit", Run: tracksCommand},
+		{Name: "upload", Description: "build playlist in configured service", Run: uploadCommand},
+		{Name: "batch", Description: "build playlists described in job file in one session", Run: batchCommand},
+		{Name: "export", Description: "write filtered discography as xml, csv or json", Run: exportCommand},
+		{Name: "plan", Description: "show which tracks will be added to the playlist", Run: planCommand},
+		{Name: "cache", Description: "show or clear local cache of the playlist", Run: cacheCommand},
+		{Name: "playlists", Description: "list playlists known to local cache", Run: playlistsCommand},
+		{Name: "cover", Description: "render playlist cover image to PNG or JPEG file", Run: coverCommand},
+		{Name: "stats", Description: "report statistics of discography tracks", Run: statsCommand},
+		{Name: "lint", Description: "check discography files for problems and fix safe ones", Run: lintCommand},
+		{Name: "aliases", Description: "list orchestra and artist names unknown to alias registry", Run: aliasesCommand},
+		{Name: "overrides", Description: "list manual matches of tracks or add tracks of the report", Run: overridesCommand},
+		{Name: "serve", Description: "run HTTP API server to preview and build playlists", Run: serveCommand},
+	}
+}
+
+// helper function to find command by its name
+func findCommand(name string) (Command, bool) {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd, true
+		}
+	}
+	return Command{}, false
+}
+
+// helper function to print usage of goplaylist
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: goplaylist <command> [options]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.Name, cmd.Description)
+	}
+	fmt.Fprintf(os.Stderr, "\nUse \"goplaylist <command> -h\" for more information about a command.\n")
+	fmt.Fprintf(os.Stderr, "Legacy invocation \"goplaylist -config ... -file ... [-tracks]\" is still supported.\n")
+}
+
+// Options represents common command line options of sub-commands
+type Options struct {
+	File        string
+	Config      string
+	Title       string
+	SortBy      string
+	SortOrder   string
+	FilterBy    string
+	Service     string
+	Profile     string
+	Privacy     string
+	Description string
+	Cover       string
+	SplitBy     string
+	Dedupe      string
+	Report      string // json or csv file of the run report
+	Strict      bool   // abort on the first unreadable discography file
+	Workers     int    // number of discography files read concurrently
+}
+
+// PlaylistSpec represents playlist title along with its tracks
+type PlaylistSpec struct {
+	Title       string
+	Discography *Discography
+}
+
+// helper function to create flag set of the command
+func newFlagSet(name, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: goplaylist %s [options]\n%s\n\nOptions:\n", name, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// helper function to register flags to read and select discography tracks
+func (o *Options) discographyFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.File, "file", "", "xml or csv file to read, may be a glob pattern")
+	fs.StringVar(&o.SortBy, "sortBy", "", "sort tracks by attribute: orchestra, artist, year, genre, vocal, composer, author, label, matrix, duration, isrc")
+	fs.StringVar(&o.SortOrder, "sortOrder", "ascending", "sort order: ascending or descending")
+	fs.StringVar(&o.FilterBy, "filterBy", "", "filter tracks conditions: {key:value, key:value}")
+	fs.BoolVar(&o.Strict, "strict", false, "abort if any of discography files can not be read, by default such files are reported and skipped")
+	fs.IntVar(&o.Workers, "workers", 0, "number of discography files read concurrently, 0 means number of CPUs")
+	o.dedupeFlags(fs)
+}
+
+// helper function to register flag which controls removal of duplicate tracks
+func (o *Options) dedupeFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Dedupe, "dedupe", "exact", "remove duplicate tracks: "+strings.Join(discography.DedupePolicies, ", "))
+}
+
+// helper function to register flag to split discography into many playlists
+func (o *Options) splitFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.SplitBy, "splitBy", "", "split tracks into playlists by attribute: genre, vocal, orchestra or decade")
+}
+
+// helper function to register flags to identify playlist in a service
+func (o *Options) playlistFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Config, "config", "", "configuration file")
+	fs.StringVar(&o.Title, "title", "", "title of the playlist")
+	fs.StringVar(&o.Service, "service", "", "service to use, overwrites service from configuration")
+	fs.StringVar(&o.Profile, "profile", "", "configuration profile to use")
+}
+
+// helper function to register flags which control created playlists
+func (o *Options) uploadFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Privacy, "privacy", "", "playlist privacy: public, private or unlisted, overwrites configuration")
+	fs.StringVar(&o.Cover, "cover", "", "playlist cover image: path to PNG/JPEG file or 'auto' to generate it")
+	fs.StringVar(&o.Description, "description", "", "playlist description template, e.g. \"{orchestras}, {years}\"")
+	fs.StringVar(&o.Report, "report", "", "write outcome of every track to json or csv report file")
+}
+
+// helper function to load configuration, if required is false the
+// configuration file is parsed only when it is provided
+func (o *Options) loadConfig(required bool) error {
+	if o.Config == "" && !required {
+		return nil
+	}
+	if o.Config == "" {
+		return errors.New("configuration file is required, please provide -config option")
+	}
+	if err := parseConfig(o.Config, o.Profile); err != nil {
+		return fmt.Errorf("fail to parse config file %s, error %v", o.Config, err)
+	}
+	if Config.Verbose > 0 {
+		log.SetFlags(log.LstdFlags | log.Lshortfile)
+	}
+	return nil
+}
+
+// helper function to read discography file and apply filters and sorting to it
+func (o *Options) readDiscography() (*Discography, error) {
+	if o.File == "" {
+		return nil, errors.New("no input file is provided, please use -file option")
+	}
+	var filters map[string]string
+	if o.FilterBy != "" {
+		if err := json.Unmarshal([]byte(o.FilterBy), &filters); err != nil {
+			return nil, fmt.Errorf("unable to parse filterBy '%s': %w", o.FilterBy, err)
+		}
+	}
+	return o.loadDiscography(filters)
+}
+
+// helper function to read discography file(s) concurrently, apply filters
+// and sorting and remove duplicate tracks according to dedupe policy, every
+// unreadable file (unless strict option is set) and every dropped track is
+// reported
+func (o *Options) loadDiscography(filters map[string]string) (*Discography, error) {
+	disc, fileErrors, err := discography.Load(o.File, discography.LoadOptions{Workers: o.Workers, Strict: o.Strict})
+	if err != nil {
+		return nil, err
+	}
+	for _, ferr := range fileErrors {
+		log.Printf("skipping discography file: %v", ferr)
+	}
+	prepareDiscography(disc, o.SortBy, o.SortOrder, filters)
+	dropped, err := disc.RemoveDuplicates(o.Dedupe)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dropped {
+		log.Printf("dropped duplicate track: %s, merged into: %s", d.Track.String(), d.MergedInto.String())
+	}
+	return disc, nil
+}
+
+// helper function to determine playlist title, it is either provided via
+// option, configuration or is taken from name of the file, the title may be
+// a template with {placeholder} entries which are expanded with facts of
+// given discography, e.g. "{orchestra} - {genre} ({minYear}-{maxYear})"
+func (o *Options) playlistTitle(discography *Discography) (string, error) {
+	// by default use playlist title
+	ptitle := Config.PlaylistTitle
+	if ptitle == "" {
+		// if it is not parsed from input file we'll use name of the file itself
+		ptitle = fileTitle(o.File)
+	}
+	if o.Title != "" {
+		// use title provided via option
+		ptitle = o.Title
+	}
+	if isTemplate(ptitle) && discography != nil {
+		ptitle = expandTemplate(ptitle, discographyFacts(fileTitle(o.File), discography))
+	}
+	if ptitle == "" {
+		return "", errors.New("empty playlist title")
+	}
+	return ptitle, nil
+}
+
+// helper function to construct playlists from discography, it is either a
+// single playlist or, if split attribute is provided, one playlist per
+// attribute value, their titles are expanded from title template where
+// {group} placeholder holds value of split attribute, if template does not
+// have any placeholders the value is appended to the title; playlists which
+// exceed size limit of the service are split into numbered parts
+func (o *Options) playlists(discography *Discography) ([]PlaylistSpec, error) {
+	specs, err := o.splitPlaylists(discography)
+	if err != nil {
+		return nil, err
+	}
+	size := maxPlaylistSize(o.serviceName())
+	var parts []PlaylistSpec
+	for _, spec := range specs {
+		parts = append(parts, playlistParts(spec, size)...)
+	}
+	return parts, nil
+}
+
+// helper function to split discography into playlists by split attribute
+func (o *Options) splitPlaylists(discography *Discography) ([]PlaylistSpec, error) {
+	if o.SplitBy == "" {
+		ptitle, err := o.playlistTitle(discography)
+		if err != nil {
+			return nil, err
+		}
+		return []PlaylistSpec{{Title: ptitle, Discography: discography}}, nil
+	}
+	groups, err := discography.Split(strings.ToLower(o.SplitBy))
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := o.playlistTitle(nil)
+	if err != nil {
+		return nil, err
+	}
+	if !isTemplate(tmpl) {
+		tmpl += " - {group}"
+	}
+	var specs []PlaylistSpec
+	titles := make(map[string]bool)
+	for _, group := range groups {
+		facts := discographyFacts(fileTitle(o.File), group.Discography)
+		facts.Group = group.Value
+		ptitle := expandTemplate(tmpl, facts)
+		if titles[ptitle] {
+			return nil, fmt.Errorf("split playlists have the same title '%s', please use {group} placeholder in the title", ptitle)
+		}
+		titles[ptitle] = true
+		specs = append(specs, PlaylistSpec{Title: ptitle, Discography: group.Discography})
+	}
+	return specs, nil
+}
+
+// helper function to construct title from name of xml or csv file
+func fileTitle(fname string) string {
+	arr := strings.Split(fname, "/")
+	if strings.HasSuffix(fname, ".xml") {
+		return strings.Replace(arr[len(arr)-1], ".xml", "", -1)
+	} else if strings.HasSuffix(fname, ".csv") {
+		return strings.Replace(arr[len(arr)-1], ".csv", "", -1)
+	}
+	return ""
+}
+
+// helper function to determine service name
+func (o *Options) serviceName() string {
+	if o.Service != "" {
+		return strings.ToLower(o.Service)
+	}
+	if Config.Service != "" {
+		return strings.ToLower(Config.Service)
+	}
+	return "youtube"
+}
+
+// helper function to print discography tracks
+func printTracks(discography *Discography) {
+	for idx, track := range discography.Tracks {
+		fmt.Printf("%4d %s\n", idx, formatTrack(track))
+	}
+}
+
+// helper function to format track in compact form, e.g.
+// {Orchestra:Anibal Troilo Year:1951 Name:La cumparsita Genre:Tango},
+// only attributes which are set are shown
+func formatTrack(track Track) string {
+	attrs := [][2]string{
+		{"Orchestra", track.Orchestra}, {"Year", string(track.Year)}, {"Name", track.Name},
+		{"Artist", track.Artist}, {"Genre", track.Genre}, {"Vocal", track.Vocal},
+		{"Composer", track.Composer}, {"Author", track.Author}, {"Label", track.Label},
+		{"Matrix", track.Matrix}, {"Duration", track.Duration}, {"ISRC", track.ISRC},
+		{"SpotifyID", track.SpotifyID}, {"YoutubeID", track.YoutubeID},
+	}
+	var keys []string
+	for key := range track.Extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		attrs = append(attrs, [2]string{key, track.Extra[key]})
+	}
+	var fields []string
+	for _, attr := range attrs {
+		if attr[1] != "" {
+			fields = append(fields, attr[0]+":"+attr[1])
+		}
+	}
+	return "{" + strings.Join(fields, " ") + "}"
+}
+
+// helper function to print tracks of the playlists
+func printPlaylists(specs []PlaylistSpec) {
+	for idx, spec := range specs {
+		if idx > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("playlist '%s' (%d tracks)\n", spec.Title, len(spec.Discography.Tracks))
+		printTracks(spec.Discography)
+	}
+}
+
+// helper function to print tracks of discography, when tracks are split
+// into many playlists they are printed per playlist
+func (o *Options) showTracks(discography *Discography) error {
+	specs, err := o.playlists(discography)
+	if err != nil {
+		if o.SplitBy != "" {
+			return err
+		}
+		// title is not required to show tracks of a single playlist
+		specs = []PlaylistSpec{{Discography: discography}}
+	}
+	if len(specs) == 1 {
+		printTracks(discography)
+		return nil
+	}
+	printPlaylists(specs)
+	return nil
+}
+
+// tracksCommand shows discography tracks
+func tracksCommand(args []string) error {
+	var opts Options
+	fs := newFlagSet("tracks", "Read discography file(s), apply filters and sorting and print found tracks.")
+	opts.discographyFlags(fs)
+	opts.splitFlags(fs)
+	fs.StringVar(&opts.Config, "config", "", "configuration file (optional)")
+	fs.StringVar(&opts.Title, "title", "", "title of the playlist, used to preview titles of split playlists")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.loadConfig(false); err != nil {
+		return err
+	}
+	Config.Verbose = 0
+	discography, err := opts.readDiscography()
+	if err != nil {
+		return err
+	}
+	return opts.showTracks(discography)
+}
+
+// uploadCommand builds playlist in configured service
+func uploadCommand(args []string) error {
+	var opts Options
+	fs := newFlagSet("upload", "Read discography file(s) and build playlist in configured service.")
+	opts.discographyFlags(fs)
+	opts.splitFlags(fs)
+	opts.playlistFlags(fs)
+	opts.uploadFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.loadConfig(true); err != nil {
+		return err
+	}
+	return runUpload(opts)
+}
+
+// helper function to read discography and upload it to the service
+func runUpload(opts Options) error {
+	service := opts.serviceName()
+	if opts.Privacy != "" {
+		Config.Privacy = opts.Privacy
+	}
+	if opts.Description != "" {
+		Config.Description = opts.Description
+	}
+	if opts.Cover != "" {
+		Config.Cover = opts.Cover
+	}
+	// validate configuration before we start any OAuth flow
+	if err := Config.Validate(service); err != nil {
+		return err
+	}
+	if opts.Report != "" {
+		if _, err := reportFormat(opts.Report); err != nil {
+			return err
+		}
+	}
+
+	// read provided file
+	discography, err := opts.readDiscography()
+	if err != nil {
+		return fmt.Errorf("error reading discography file: %w", err)
+	}
+	specs, err := opts.playlists(discography)
+	if err != nil {
+		return err
+	}
+	popts := Config.playlistOptions()
+	// cover given on command line replaces cover of existing playlists
+	popts.ReplaceCover = opts.Cover != ""
+	return uploadPlaylists(service, specs, popts, opts.Report)
+}
+
+// helper function to upload playlists to given service within one session,
+// it prints summary of the run and writes its report if report file is given
+func uploadPlaylists(service string, specs []PlaylistSpec, popts PlaylistOptions, report string) error {
+	opts := SessionOptions{Privacies: []string{playlistPrivacy()}, Cover: Config.Cover != ""}
+	session, err := openSession(service, opts)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	run := RunReport{Started: time.Now()}
+	for _, spec := range specs {
+		fmt.Printf("creating %s playlist: %s\n", service, spec.Title)
+		result, err := session.Build(spec.Title, spec.Discography, popts)
+		playlist := PlaylistReport{Service: service, BuildResult: result}
+		if err != nil {
+			playlist.Error = err.Error()
+			run.Playlists = append(run.Playlists, playlist)
+			return finishRun(run, report, fmt.Errorf("couldn't build playlist: %w", err))
+		}
+		run.Playlists = append(run.Playlists, playlist)
+		log.Printf("New playlist %s is created: %s", spec.Title, result.URL)
+	}
+	return finishRun(run, report, nil)
+}
+
+// helper function to print summary of the run and write its report, it
+// returns given error of the run or error of writing the report
+func finishRun(run RunReport, report string, err error) error {
+	run.Finished = time.Now()
+	printSummary(os.Stdout, run.Playlists)
+	if report == "" {
+		return err
+	}
+	if rerr := writeReport(report, run); rerr != nil {
+		return errors.Join(err, fmt.Errorf("unable to write report %s: %w", report, rerr))
+	}
+	fmt.Println("report of the run is written to", report)
+	return err
+}
+
+// exportCommand writes filtered discography to a file
+func exportCommand(args []string) error {
+	var opts Options
+	var format, output string
+	fs := newFlagSet("export", "Read discography file(s), apply filters and sorting and write resulting tracks.")
+	opts.discographyFlags(fs)
+	fs.StringVar(&format, "format", "", "output format: xml, csv or json (default is taken from output file extension or xml)")
+	fs.StringVar(&output, "output", "", "output file (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(output), ".")
+		if format == "" {
+			format = "xml"
+		}
+	}
+	discography, err := opts.readDiscography()
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	return discography.Write(w, strings.ToLower(format))
+}
+
+// planCommand shows which tracks are already in the playlist and which
+// will be looked up in the service, it only uses local cache
+func planCommand(args []string) error {
+	var opts Options
+	fs := newFlagSet("plan", "Show which tracks will be added to the playlist and which are already in local cache.")
+	opts.discographyFlags(fs)
+	opts.splitFlags(fs)
+	opts.playlistFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.loadConfig(false); err != nil {
+		return err
+	}
+	discography, err := opts.readDiscography()
+	if err != nil {
+		return err
+	}
+	specs, err := opts.playlists(discography)
+	if err != nil {
+		return err
+	}
+	service := opts.serviceName()
+	pcache := &Cache{Dir: cacheDir(service)}
+	playlists, err := pcache.Playlists()
+	if err != nil {
+		return err
+	}
+	for _, spec := range specs {
+		var cached []Track
+		for _, p := range playlists {
+			if p.Title == cacheName(spec.Title) {
+				tracks, err := pcache.Load(service, p.Title, p.ID)
+				if err != nil {
+					return err
+				}
+				cached = append(cached, tracks...)
+			}
+		}
+
+		var add, skip, never int
+		for idx, trk := range playlistTracks(spec.Title, spec.Discography) {
+			action := "add"
+			if o := trackOverrides.Lookup(trk); o != nil && o.Never {
+				action = "never"
+				never++
+			} else if inList(trk, cached) {
+				action = "skip"
+				skip++
+			} else {
+				add++
+			}
+			fmt.Printf("%4d %-5s %s\n", idx, action, trk.String())
+		}
+		fmt.Printf("%s playlist '%s': %d tracks to add, %d tracks already in cache, %d tracks never added\n",
+			service, spec.Title, add, skip, never)
+	}
+	return nil
+}
+
+// cacheCommand shows or clears local cache of the playlist
+func cacheCommand(args []string) error {
+	var service, title string
+	var clear bool
+	fs := newFlagSet("cache", "Show cached tracks of the playlist or clear its cache.")
+	fs.StringVar(&service, "service", "youtube", "service name")
+	fs.StringVar(&title, "title", "", "title of the playlist")
+	fs.BoolVar(&clear, "clear", false, "remove cache of the playlist")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if title == "" {
+		return errors.New("no playlist title is provided, please use -title option")
+	}
+	pcache := &Cache{Dir: cacheDir(strings.ToLower(service))}
+	if clear {
+		if err := pcache.Remove(title); err != nil {
+			return err
+		}
+		fmt.Printf("cache of %s playlist '%s' is removed\n", service, title)
+		return nil
+	}
+	playlists, err := pcache.Playlists()
+	if err != nil {
+		return err
+	}
+	for _, p := range playlists {
+		if p.Title != cacheName(title) {
+			continue
+		}
+		fmt.Printf("playlist '%s' ID %s\n", p.Title, p.ID)
+		tracks, err := pcache.Load(service, p.Title, p.ID)
+		if err != nil {
+			return err
+		}
+		for idx, track := range tracks {
+			fmt.Printf("%4d %s\n", idx, track.String())
+		}
+		ids, err := pcache.LoadIDs(p.Title, p.ID)
+		if err != nil {
+			return err
+		}
+		var keys []string
+		for track := range ids {
+			keys = append(keys, track)
+		}
+		sort.Strings(keys)
+		for _, track := range keys {
+			fmt.Printf("     %s -> %s\n", track, ids[track])
+		}
+	}
+	return nil
+}
+
+// playlistsCommand lists playlists known to local cache
+func playlistsCommand(args []string) error {
+	var service string
+	fs := newFlagSet("playlists", "List playlists known to local cache.")
+	fs.StringVar(&service, "service", "", "service name (default all services)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	names := services
+	if service != "" {
+		names = []string{strings.ToLower(service)}
+		if !slices.Contains(services, names[0]) {
+			return fmt.Errorf("unknown service '%s', supported services: %s", service, strings.Join(services, ", "))
+		}
+	}
+	for _, srv := range names {
+		pcache := &Cache{Dir: cacheDir(srv)}
+		playlists, err := pcache.Playlists()
+		if err != nil {
+			return err
+		}
+		for _, p := range playlists {
+			fmt.Printf("%-9s %-40s %s (%d tracks)\n", srv, p.Title, p.ID, p.Tracks)
+		}
+	}
+	return nil
+}
+
+// coverCommand renders playlist cover image and writes it to a file
+func coverCommand(args []string) error {
+	var opts Options
+	var output string
+	fs := newFlagSet("cover", "Render playlist cover image with title, orchestras and year range of the discography.")
+	opts.discographyFlags(fs)
+	fs.StringVar(&opts.Config, "config", "", "configuration file with cover themes (optional)")
+	fs.StringVar(&opts.Title, "title", "", "title of the playlist")
+	fs.StringVar(&output, "output", "", "output file, png or jpg (default <title>.png)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.loadConfig(false); err != nil {
+		return err
+	}
+	discography, err := opts.readDiscography()
+	if err != nil {
+		return err
+	}
+	ptitle, err := opts.playlistTitle(discography)
+	if err != nil {
+		return err
+	}
+	img, err := renderCover(discographyFacts(ptitle, discography))
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		output = safeFileName(ptitle) + ".png"
+	}
+	if err := writeCover(output, img); err != nil {
+		return err
+	}
+	fmt.Println("cover image is written to", output)
+	return nil
+}
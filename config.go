@@ -7,37 +7,197 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 )
 
 // Configuration stores server configuration parameters
 type Configuration struct {
-	YoutubeId     string `json:"youtube_id"`
-	YoutubeSecret string `json:"youtube_secret"`
-	SpotifyId     string `json:"spotify_id"`
-	SpotifySecret string `json:"spotify_secret"`
-	CallbackPort  int    `json:"callback_port"`
-	Service       string `json:"service"`
-	PlaylistTitle string `json:"playlist_title"`
-	Verbose       int    `json:"verbose"`
+	YoutubeId        string `json:"youtube_id"`
+	YoutubeSecret    string `json:"youtube_secret"`
+	SpotifyId        string `json:"spotify_id"`
+	SpotifySecret    string `json:"spotify_secret"`
+	SubsonicURL      string `json:"subsonic_url"`
+	SubsonicUser     string `json:"subsonic_user"`
+	SubsonicPassword string `json:"subsonic_password"`
+	MPDAddress       string `json:"mpd_address"`
+	MPDPassword      string `json:"mpd_password"`
+	MPDLoad          bool   `json:"mpd_load"`
+	MusicDir         string `json:"music_dir"`
+	OutputDir        string `json:"output_dir"`
+	CallbackPort     int    `json:"callback_port"`
+	Service          string `json:"service"`
+	PlaylistTitle    string `json:"playlist_title"`
+	Privacy          string `json:"privacy"`
+	Description      string `json:"description"`
+	Cover            string `json:"cover"`
+	MaxPlaylistSize  int    `json:"max_playlist_size"`
+	MinScore         int    `json:"min_score"` // matches scored below it (0-100) are not added
+	Verbose          int    `json:"verbose"`
+
+	// color themes of generated cover images per genre
+	CoverThemes map[string]CoverTheme `json:"cover_themes,omitempty"`
+
+	// named profiles, every profile may overwrite any parameter above
+	Profiles       map[string]json.RawMessage `json:"profiles,omitempty"`
+	DefaultProfile string                     `json:"default_profile,omitempty"`
+	Profile        string                     `json:"-"` // name of profile in use
 }
 
 // Config variable represents configuration object
 var Config Configuration
 
-// helper function to parse server configuration file
-func parseConfig(configFile string) error {
+// envPrefix defines prefix of environment variables which overwrite
+// configuration parameters, e.g. GOPLAYLIST_SPOTIFY_SECRET
+const envPrefix = "GOPLAYLIST_"
+
+// helper function to parse server configuration file, the parameters of
+// given profile (or default profile of configuration, or profile set via
+// GOPLAYLIST_PROFILE environment) are applied on top of common ones and
+// then GOPLAYLIST_* environment variables are applied
+func parseConfig(configFile, profile string) error {
 	data, err := os.ReadFile(filepath.Clean(configFile))
 	if err != nil {
 		log.Println("Unable to read", err)
 		return err
 	}
-	err = json.Unmarshal(data, &Config)
+	var config Configuration
+	err = json.Unmarshal(data, &config)
 	if err != nil {
 		log.Println("Unable to parse", err)
 		return err
 	}
+	if profile == "" {
+		profile = os.Getenv(envPrefix + "PROFILE")
+	}
+	if profile == "" {
+		profile = config.DefaultProfile
+	}
+	if profile != "" {
+		pdata, ok := config.Profiles[profile]
+		if !ok {
+			return fmt.Errorf("unknown profile '%s' in config file %s", profile, configFile)
+		}
+		if err := json.Unmarshal(pdata, &config); err != nil {
+			return fmt.Errorf("unable to parse profile '%s': %w", profile, err)
+		}
+		config.Profile = profile
+	}
+	if err := config.applyEnv(); err != nil {
+		return err
+	}
+	Config = config
+	return nil
+}
+
+// helper function to overwrite configuration parameters from environment,
+// the variable name is upper case json name of parameter with GOPLAYLIST_
+// prefix, e.g. GOPLAYLIST_SPOTIFY_SECRET or GOPLAYLIST_CALLBACK_PORT
+func (c *Configuration) applyEnv() error {
+	val := reflect.ValueOf(c).Elem()
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Type().Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		key := envPrefix + strings.ToUpper(name)
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.String:
+			val.Field(i).SetString(value)
+		case reflect.Int:
+			v, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid value of %s: %w", key, err)
+			}
+			val.Field(i).SetInt(int64(v))
+		case reflect.Bool:
+			v, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid value of %s: %w", key, err)
+			}
+			val.Field(i).SetBool(v)
+		}
+	}
+	return nil
+}
+
+// services lists names of supported services
+var services = []string{"spotify", "youtube", "subsonic", "navidrome", "mpd", "local"}
+
+// Validate checks that configuration provides all parameters required by
+// given service, it reports all problems at once
+func (c *Configuration) Validate(service string) error {
+	var errs []error
+	if !slices.Contains(services, service) {
+		errs = append(errs, fmt.Errorf("unknown service '%s', supported services: %s", service, strings.Join(services, ", ")))
+	}
+	missing := func(name, value string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("missing %s", name))
+		}
+	}
+	oauth := false
+	switch service {
+	case "spotify":
+		missing("spotify_id", c.SpotifyId)
+		missing("spotify_secret", c.SpotifySecret)
+		oauth = true
+	case "youtube":
+		missing("youtube_id", c.YoutubeId)
+		missing("youtube_secret", c.YoutubeSecret)
+		oauth = true
+	case "subsonic", "navidrome":
+		missing("subsonic_url", c.SubsonicURL)
+		missing("subsonic_user", c.SubsonicUser)
+		missing("subsonic_password", c.SubsonicPassword)
+	case "local":
+		missing("music_dir", c.MusicDir)
+	}
+	switch strings.ToLower(c.Privacy) {
+	case "", "public", "private", "unlisted":
+	default:
+		errs = append(errs, fmt.Errorf("invalid privacy '%s', should be public, private or unlisted", c.Privacy))
+	}
+	if c.MinScore < 0 || c.MinScore > 100 {
+		errs = append(errs, fmt.Errorf("invalid min_score %d, should be between 0 and 100", c.MinScore))
+	}
+	if oauth && (c.CallbackPort <= 0 || c.CallbackPort > 65535) {
+		errs = append(errs, fmt.Errorf("invalid callback_port %d", c.CallbackPort))
+	}
+	if len(errs) > 0 {
+		msg := fmt.Sprintf("invalid configuration for %s service", service)
+		if c.Profile != "" {
+			msg = fmt.Sprintf("%s (profile %s)", msg, c.Profile)
+		}
+		return fmt.Errorf("%s: %w", msg, errors.Join(errs...))
+	}
 	return nil
 }
+
+// helper function to get privacy of playlists, i.e. public, private or
+// unlisted, services which do not support unlisted playlists treat them
+// as private ones
+func playlistPrivacy() string {
+	return Config.playlistOptions().Privacy
+}
+
+// helper function to get options of playlists built with the configuration
+func (c *Configuration) playlistOptions() PlaylistOptions {
+	privacy := strings.ToLower(c.Privacy)
+	if privacy == "" {
+		privacy = "public"
+	}
+	return PlaylistOptions{Privacy: privacy, Description: c.Description, Cover: c.Cover}
+}
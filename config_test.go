@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// helper function to write configuration file
+func writeConfig(t *testing.T, content string) string {
+	fname := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(fname, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fname
+}
+
+func TestConfigProfiles(t *testing.T) {
+	fname := writeConfig(t, `{
+    "service": "spotify",
+    "callback_port": 8888,
+    "spotify_id": "common-id",
+    "default_profile": "personal",
+    "profiles": {
+        "personal": {"spotify_secret": "personal-secret"},
+        "school": {"service": "youtube", "youtube_id": "school-id", "callback_port": 9999}
+    }
+}`)
+	defer func() { Config = Configuration{} }()
+
+	if err := parseConfig(fname, ""); err != nil {
+		t.Fatal(err)
+	}
+	if Config.Profile != "personal" || Config.SpotifyId != "common-id" || Config.SpotifySecret != "personal-secret" {
+		t.Errorf("wrong default profile configuration %+v", Config)
+	}
+
+	if err := parseConfig(fname, "school"); err != nil {
+		t.Fatal(err)
+	}
+	if Config.Service != "youtube" || Config.CallbackPort != 9999 || Config.SpotifySecret != "" {
+		t.Errorf("wrong school profile configuration %+v", Config)
+	}
+
+	if err := parseConfig(fname, "unknown"); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
+
+func TestConfigEnv(t *testing.T) {
+	fname := writeConfig(t, `{"service": "youtube", "callback_port": 8888, "youtube_id": "id"}`)
+	defer func() { Config = Configuration{} }()
+
+	t.Setenv("GOPLAYLIST_YOUTUBE_SECRET", "env-secret")
+	t.Setenv("GOPLAYLIST_CALLBACK_PORT", "7777")
+	t.Setenv("GOPLAYLIST_MPD_LOAD", "true")
+	if err := parseConfig(fname, ""); err != nil {
+		t.Fatal(err)
+	}
+	if Config.YoutubeSecret != "env-secret" || Config.CallbackPort != 7777 || !Config.MPDLoad {
+		t.Errorf("environment is not applied to configuration %+v", Config)
+	}
+
+	t.Setenv("GOPLAYLIST_CALLBACK_PORT", "port")
+	if err := parseConfig(fname, ""); err == nil {
+		t.Error("expected error for invalid GOPLAYLIST_CALLBACK_PORT")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	config := Configuration{SpotifyId: "id", CallbackPort: 70000}
+	err := config.Validate("spotify")
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, msg := range []string{"missing spotify_secret", "invalid callback_port 70000"} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("validation error '%v' does not report '%s'", err, msg)
+		}
+	}
+	if err := config.Validate("napster"); err == nil || !strings.Contains(err.Error(), "unknown service") {
+		t.Errorf("expected unknown service error, got %v", err)
+	}
+
+	config = Configuration{YoutubeId: "id", YoutubeSecret: "secret", CallbackPort: 8888}
+	if err := config.Validate("youtube"); err != nil {
+		t.Errorf("unexpected validation error %v", err)
+	}
+	if err := config.Validate("mpd"); err != nil {
+		t.Errorf("unexpected validation error %v", err)
+	}
+}
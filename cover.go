@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// coverSize defines size (in pixels) of square cover image
+const coverSize = 640
+
+// spotifyCoverLimit defines maximum size of JPEG cover accepted by Spotify,
+// the API limits base64 encoded payload to 256 KB
+const spotifyCoverLimit = 256 * 1024 * 3 / 4
+
+// CoverTheme represents color theme of the cover image, colors are
+// provided in hex notation, e.g. #2b0a0f
+type CoverTheme struct {
+	Background string `json:"background"`
+	Foreground string `json:"foreground"`
+	Accent     string `json:"accent"`
+}
+
+// defaultCoverThemes defines color themes of cover images per genre
+var defaultCoverThemes = map[string]CoverTheme{
+	"tango":   {Background: "#2b0a0f", Foreground: "#f5e6c8", Accent: "#c9a227"},
+	"vals":    {Background: "#0f1d3a", Foreground: "#eef2fb", Accent: "#8fb3ff"},
+	"milonga": {Background: "#123524", Foreground: "#f1f7ee", Accent: "#e3b341"},
+	"default": {Background: "#1c1c1c", Foreground: "#f0f0f0", Accent: "#b0b0b0"},
+}
+
+// CoverUploader is implemented by providers which support playlist cover images
+type CoverUploader interface {
+	// UploadCover replaces cover image of the playlist
+	UploadCover(playlistID string, img image.Image) error
+}
+
+// helper function to find cover theme for given genre, themes from
+// configuration take precedence over default ones
+func coverTheme(genre string) CoverTheme {
+	genre = strings.ToLower(genre)
+	for _, themes := range []map[string]CoverTheme{Config.CoverThemes, defaultCoverThemes} {
+		if theme, ok := themes[genre]; ok {
+			return theme
+		}
+	}
+	if theme, ok := Config.CoverThemes["default"]; ok {
+		return theme
+	}
+	return defaultCoverThemes["default"]
+}
+
+// helper function to parse hex color, e.g. #2b0a0f
+func parseHexColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color '%s'", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color '%s': %w", s, err)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+// helper function to create font face of given size from embedded TTF font
+func coverFace(ttf []byte, size float64) (font.Face, error) {
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		return nil, err
+	}
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// helper function to split text into lines which fit given width
+func wrapText(face font.Face, text string, width int) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if line != "" && font.MeasureString(face, candidate).Ceil() > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line = candidate
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// helper function to draw lines of text centered horizontally starting at y
+// position, it returns y position after the last line
+func drawLines(img draw.Image, face font.Face, col color.Color, lines []string, y int) int {
+	drawer := &font.Drawer{Dst: img, Src: image.NewUniform(col), Face: face}
+	height := face.Metrics().Height.Ceil()
+	for _, line := range lines {
+		y += height
+		x := (coverSize - drawer.MeasureString(line).Ceil()) / 2
+		drawer.Dot = fixed.P(x, y)
+		drawer.DrawString(line)
+	}
+	return y
+}
+
+// helper function to render playlist cover image with playlist title,
+// orchestras and year range, colors are taken from the theme of dominant genre
+func renderCover(facts PlaylistFacts) (image.Image, error) {
+	genre := "default"
+	if len(facts.Genres) > 0 {
+		genre = facts.Genres[0].Value
+	}
+	theme := coverTheme(genre)
+	var colors []color.RGBA
+	for _, c := range []string{theme.Background, theme.Foreground, theme.Accent} {
+		col, err := parseHexColor(c)
+		if err != nil {
+			return nil, err
+		}
+		colors = append(colors, col)
+	}
+	bg, fg, accent := colors[0], colors[1], colors[2]
+
+	titleFace, err := coverFace(gobold.TTF, 56)
+	if err != nil {
+		return nil, err
+	}
+	textFace, err := coverFace(goregular.TTF, 30)
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, coverSize, coverSize))
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	margin := 40
+	width := coverSize - 2*margin
+	// accent frame
+	for _, r := range []image.Rectangle{
+		image.Rect(margin/2, margin/2, coverSize-margin/2, margin/2+4),
+		image.Rect(margin/2, coverSize-margin/2-4, coverSize-margin/2, coverSize-margin/2),
+	} {
+		draw.Draw(img, r, image.NewUniform(accent), image.Point{}, draw.Src)
+	}
+
+	// text blocks of the cover: genres, title, orchestras and years
+	type textBlock struct {
+		face  font.Face
+		color color.Color
+		lines []string
+		gap   int
+	}
+	var genres []string
+	for _, g := range facts.Genres {
+		genres = append(genres, strings.ToUpper(g.Value))
+	}
+	titleLines := wrapText(titleFace, facts.Title, width)
+	if len(titleLines) > 5 {
+		titleLines = titleLines[:5]
+	}
+	var orchestras []string
+	for i, o := range facts.Orchestras {
+		if i == 3 {
+			orchestras = append(orchestras, "...")
+			break
+		}
+		orchestras = append(orchestras, o.Value)
+	}
+	blocks := []textBlock{
+		{textFace, accent, wrapText(textFace, strings.Join(genres, " · "), width), 0},
+		{titleFace, fg, titleLines, margin},
+		{textFace, fg, wrapText(textFace, strings.Join(orchestras, ", "), width), margin / 2},
+		{textFace, accent, []string{facts.YearRange()}, margin / 4},
+	}
+
+	// center text blocks vertically
+	height := 0
+	for _, b := range blocks {
+		height += b.gap + len(b.lines)*b.face.Metrics().Height.Ceil()
+	}
+	y := (coverSize - height) / 2
+	for _, b := range blocks {
+		y = drawLines(img, b.face, b.color, b.lines, y+b.gap)
+	}
+	return img, nil
+}
+
+// helper function to read cover image from a file, PNG and JPEG formats are supported
+func readCover(fname string) (image.Image, error) {
+	file, err := os.Open(filepath.Clean(fname))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode cover image %s: %w", fname, err)
+	}
+	return img, nil
+}
+
+// helper function to write cover image to a file, the format is
+// determined by file extension, i.e. png or jpg/jpeg
+func writeCover(fname string, img image.Image) error {
+	var buf bytes.Buffer
+	var err error
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".png":
+		err = png.Encode(&buf, img)
+	case ".jpg", ".jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	default:
+		err = fmt.Errorf("unsupported cover image format: %s", fname)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fname, buf.Bytes(), 0644)
+}
+
+// helper function to encode image as JPEG which does not exceed given size,
+// it reduces JPEG quality until image fits the limit
+func encodeJPEG(img image.Image, limit int) ([]byte, error) {
+	for quality := 90; quality >= 30; quality -= 10 {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, err
+		}
+		if buf.Len() <= limit {
+			return buf.Bytes(), nil
+		}
+	}
+	return nil, fmt.Errorf("unable to fit cover image into %d bytes", limit)
+}
+
+// helper function to obtain cover image of the playlist, the cover is either
+// read from given file or rendered from discography if cover is "auto"
+func coverImage(title string, discography *Discography, cover string) (image.Image, error) {
+	if cover != "" && cover != "auto" {
+		return readCover(cover)
+	}
+	return renderCover(discographyFacts(title, discography))
+}
+
+// helper function to upload cover image of the playlist if provider supports
+// it, empty cover means that no cover is requested
+func uploadCover(p Provider, playlistID, title string, discography *Discography, cover string) {
+	if cover == "" {
+		return
+	}
+	uploader, ok := p.(CoverUploader)
+	if !ok {
+		log.Printf("%s service does not support playlist cover images", p.Name())
+		return
+	}
+	img, err := coverImage(title, discography, cover)
+	if err != nil {
+		log.Printf("Unable to create cover image: %v", err)
+		return
+	}
+	if err := uploader.UploadCover(playlistID, img); err != nil {
+		log.Printf("Unable to upload cover image: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"image"
+	"path/filepath"
+	"testing"
+)
+
+func TestCover(t *testing.T) {
+	discography, err := readFile("testplaylist.xml", "", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { Config = Configuration{} }()
+	Config.CoverThemes = map[string]CoverTheme{
+		"tango": {Background: "#000000", Foreground: "#ffffff", Accent: "#ff0000"},
+	}
+	img, err := renderCover(discographyFacts("Test playlist", discography))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != coverSize || b.Dy() != coverSize {
+		t.Errorf("wrong cover size %v", b)
+	}
+	// background color should be taken from configured tango theme
+	if r, g, b, _ := img.At(1, 1).RGBA(); r != 0 || g != 0 || b != 0 {
+		t.Errorf("wrong background color %v", img.At(1, 1))
+	}
+
+	data, err := encodeJPEG(img, spotifyCoverLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) > spotifyCoverLimit {
+		t.Errorf("cover size %d exceeds spotify limit", len(data))
+	}
+
+	for _, ext := range []string{"png", "jpg"} {
+		fname := filepath.Join(t.TempDir(), "cover."+ext)
+		if err := writeCover(fname, img); err != nil {
+			t.Fatal(err)
+		}
+		cover, err := readCover(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cover.Bounds() != img.Bounds() {
+			t.Errorf("wrong bounds of %s cover %v", ext, cover.Bounds())
+		}
+	}
+	if err := writeCover(filepath.Join(t.TempDir(), "cover.gif"), img); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+// coverProvider is fake provider which supports playlist cover images
+type coverProvider struct {
+	fakeProvider
+	covers []string // IDs of playlists whose cover is uploaded
+}
+
+func (p *coverProvider) UploadCover(playlistID string, img image.Image) error {
+	p.covers = append(p.covers, playlistID)
+	return nil
+}
+
+func TestUploadCover(t *testing.T) {
+	cache = &Cache{}
+	cache.Init("fake", t.TempDir())
+	discography := &Discography{Tracks: []Track{{Orchestra: "Francisco Canaro", Year: "1935", Name: "Poema"}}}
+	provider := &coverProvider{}
+	opts := PlaylistOptions{Cover: "auto"}
+	// cover is set when playlist is created and replaced only on request
+	for i, replace := range []bool{false, false, true} {
+		opts.ReplaceCover = replace
+		if _, err := buildPlaylist(provider, "Canaro", discography, opts, nil); err != nil {
+			t.Fatal(err)
+		}
+		if i == 1 && len(provider.covers) != 1 {
+			t.Errorf("cover of existing playlist should not be uploaded, got %v", provider.covers)
+		}
+	}
+	if len(provider.covers) != 2 {
+		t.Errorf("expected two cover uploads, got %v", provider.covers)
+	}
+}
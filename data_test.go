@@ -2,7 +2,9 @@ package main
 
 import (
 	"encoding/xml"
+	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -10,7 +12,7 @@ import (
 
 func TestXMLParsing(t *testing.T) {
 	file := "testplaylist.xml"
-	discography, err := readXMLFile(file)
+	discography, err := readFile(file, "", "", nil)
 	if err != nil {
 		t.Error(err)
 	}
@@ -30,83 +32,10 @@ func TestXMLParsing(t *testing.T) {
 
 }
 
-func TestWriteAndReadXMLFile(t *testing.T) {
-	xmlFilename := "test.xml"
-
-	expectedDiscography := &Discography{
-		Orchestra: "Ricardo Tanturi",
-		Tracks: []Track{
-			{Name: "Una noche más", Orchestra: "Ricardo Tanturi", Year: "1941"},
-			{Name: "En el salón", Orchestra: "Ricardo Tanturi", Year: "1943"},
-		},
-	}
-
-	// Write XML file
-	file, err := os.Create(xmlFilename)
-	if err != nil {
-		t.Fatalf("Failed to create XML file: %v", err)
-	}
-	defer os.Remove(xmlFilename) // Cleanup after the test
-	defer file.Close()
-
-	// Write the XML content to the file
-	encoder := xml.NewEncoder(file)
-	encoder.Indent("", "  ")
-	if err := encoder.Encode(expectedDiscography); err != nil {
-		t.Fatalf("Failed to write XML to file: %v", err)
-	}
-
-	// Read XML file back
-	discography, err := readXMLFile(xmlFilename)
-	if err != nil {
-		t.Fatalf("Failed to read XML file: %v", err)
-	}
-
-	// Compare the expected and actual results
-	if !reflect.DeepEqual(discography, expectedDiscography) {
-		t.Errorf("Expected %+v, got %+v", expectedDiscography, discography)
-	}
-}
-
 // Test writing and reading CSV
-func TestWriteAndReadCSV(t *testing.T) {
-	csvFilename := "test.csv"
-	expectedDiscography := &Discography{
-		Tracks: []Track{
-			{Name: "Una noche más", Orchestra: "Ricardo Tanturi", Year: "1941"},
-			{Name: "En el salón", Orchestra: "Ricardo Tanturi", Year: "1943"},
-		},
-	}
-
-	// Write CSV
-	csvFile, err := os.Create(csvFilename)
-	if err != nil {
-		t.Fatalf("Failed to create CSV file: %v", err)
-	}
-	defer os.Remove(csvFilename)
-	defer csvFile.Close()
-
-	for _, track := range expectedDiscography.Tracks {
-		_, err := csvFile.WriteString(track.Orchestra + "," + track.Year + "," + track.Name + "\n")
-		if err != nil {
-			t.Fatalf("Failed to write to CSV file: %v", err)
-		}
-	}
-
-	csvFile.Close()
-
-	// Read CSV
-	discography, err := readCSVFile(csvFilename)
-	if err != nil {
-		t.Fatalf("Failed to read CSV file: %v", err)
-	}
-
-	if !reflect.DeepEqual(discography, expectedDiscography) {
-		t.Errorf("Expected %+v, got %+v", expectedDiscography, discography)
-	}
-}
 
 // Test generalized readFile function
+
 func TestReadFile(t *testing.T) {
 	xmlFilename := "test.xml"
 	csvFilename := "test.csv"
@@ -127,7 +56,7 @@ func TestReadFile(t *testing.T) {
 	defer csvFile.Close()
 
 	for _, track := range expectedDiscography.Tracks {
-		_, err := csvFile.WriteString(track.Orchestra + "," + track.Year + "," + track.Name + "\n")
+		_, err := csvFile.WriteString(track.Orchestra + "," + string(track.Year) + "," + track.Name + "\n")
 		if err != nil {
 			t.Fatalf("Failed to write to CSV file: %v", err)
 		}
@@ -181,165 +110,137 @@ func TestReadFile(t *testing.T) {
 	}
 }
 
-func TestSortBy(t *testing.T) {
-	// Create a sample Discography object
-	discography := Discography{
-		Orchestra: "Orchestra1",
-		Tracks: []Track{
-			{Orchestra: "Orchestra2", Year: "2020", Name: "Track C", Artist: "Artist A", Genre: "Genre X", Vocal: "Vocal X"},
-			{Orchestra: "Orchestra3", Year: "2019", Name: "Track A", Artist: "Artist B", Genre: "Genre Z", Vocal: "Vocal Y"},
-			{Orchestra: "Orchestra1", Year: "2021", Name: "Track B", Artist: "Artist A", Genre: "Genre Y", Vocal: "Vocal Z"},
-		},
-	}
+// Test writing discography in different formats and reading it back
 
-	tests := []struct {
-		attr        string
-		order       string
-		expected    []Track
-		description string
-	}{
-		{
-			attr:  "year",
-			order: "ascending",
-			expected: []Track{
-				{Orchestra: "Orchestra3", Year: "2019", Name: "Track A", Artist: "Artist B", Genre: "Genre Z", Vocal: "Vocal Y"},
-				{Orchestra: "Orchestra2", Year: "2020", Name: "Track C", Artist: "Artist A", Genre: "Genre X", Vocal: "Vocal X"},
-				{Orchestra: "Orchestra1", Year: "2021", Name: "Track B", Artist: "Artist A", Genre: "Genre Y", Vocal: "Vocal Z"},
-			},
-			description: "Sort by year in ascending order",
-		},
-		{
-			attr:  "name",
-			order: "descending",
-			expected: []Track{
-				{Orchestra: "Orchestra2", Year: "2020", Name: "Track C", Artist: "Artist A", Genre: "Genre X", Vocal: "Vocal X"},
-				{Orchestra: "Orchestra1", Year: "2021", Name: "Track B", Artist: "Artist A", Genre: "Genre Y", Vocal: "Vocal Z"},
-				{Orchestra: "Orchestra3", Year: "2019", Name: "Track A", Artist: "Artist B", Genre: "Genre Z", Vocal: "Vocal Y"},
-			},
-			description: "Sort by name in descending order",
-		},
-		{
-			attr:  "artist",
-			order: "ascending",
-			expected: []Track{
-				{Orchestra: "Orchestra2", Year: "2020", Name: "Track C", Artist: "Artist A", Genre: "Genre X", Vocal: "Vocal X"},
-				{Orchestra: "Orchestra1", Year: "2021", Name: "Track B", Artist: "Artist A", Genre: "Genre Y", Vocal: "Vocal Z"},
-				{Orchestra: "Orchestra3", Year: "2019", Name: "Track A", Artist: "Artist B", Genre: "Genre Z", Vocal: "Vocal Y"},
-			},
-			description: "Sort by artist in ascending order",
-		},
-		{
-			attr:  "genre",
-			order: "ascending",
-			expected: []Track{
-				{Orchestra: "Orchestra2", Year: "2020", Name: "Track C", Artist: "Artist A", Genre: "Genre X", Vocal: "Vocal X"},
-				{Orchestra: "Orchestra1", Year: "2021", Name: "Track B", Artist: "Artist A", Genre: "Genre Y", Vocal: "Vocal Z"},
-				{Orchestra: "Orchestra3", Year: "2019", Name: "Track A", Artist: "Artist B", Genre: "Genre Z", Vocal: "Vocal Y"},
-			},
-			description: "Sort by genre in ascending order",
-		},
+func TestExtendedTrackFields(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "test.xml")
+	data := `<discography orchestra="Carlos Di Sarli">
+    <track name="Bahía Blanca" year="1957-11-13" genre="tango" composer="Carlos Di Sarli" label="RCA Victor" matrix="E2VB-1234" duration="2:58" isrc="AR-A01-57-00001" spotify_id="4uLU6hMCjMI75M1A2tKUQC"/>
+    <track name="Nada" year="1944" genre="tango" composer="Horacio Sanguinetti" author="Juan Carlos Thorry" duration="3:05"/>
+</discography>`
+	if err := os.WriteFile(fname, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	discography, err := readFile(fname, "duration", "descending", nil)
+	if err != nil {
+		t.Fatalf("readFile failed: %v", err)
+	}
+	track := discography.Tracks[1]
+	if track.Composer != "Carlos Di Sarli" || track.Label != "RCA Victor" || track.Matrix != "E2VB-1234" ||
+		track.Duration != "2:58" || track.ISRC != "AR-A01-57-00001" || track.ServiceID("spotify") != "4uLU6hMCjMI75M1A2tKUQC" {
+		t.Errorf("extended fields are not read: %+v", track)
+	}
+	if discography.Tracks[0].Author != "Juan Carlos Thorry" {
+		t.Errorf("expected tracks sorted by duration, got %+v", discography.Tracks)
 	}
 
-	for _, test := range tests {
-		t.Run(test.description, func(t *testing.T) {
-			// Make a copy of the original discography to sort
-			d := discography
-			d.sortBy(test.attr, test.order)
+	// extended fields survive CSV export
+	var buf strings.Builder
+	if err := discography.Write(&buf, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	csvName := filepath.Join(t.TempDir(), "test.csv")
+	if err := os.WriteFile(csvName, []byte(buf.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	csvDiscography, err := readFile(csvName, "", "", map[string]string{"isrc": "ara015700001"})
+	if err != nil {
+		t.Fatalf("readFile failed: %v", err)
+	}
+	if len(csvDiscography.Tracks) != 1 || !reflect.DeepEqual(csvDiscography.Tracks[0], track) {
+		t.Errorf("expected %+v, got %+v", track, csvDiscography.Tracks)
+	}
 
-			// Verify the result matches the expected order
-			if !reflect.DeepEqual(d.Tracks, test.expected) {
-				t.Errorf("Failed %s\nGot: %+v\nExpected: %+v", test.description, d.Tracks, test.expected)
-			}
-		})
+	if query := (&SpotifyProvider{}).Query(track); query != "isrc:ARA015700001" {
+		t.Errorf("unexpected spotify query: %s", query)
 	}
 }
 
-func TestSortByMultipleKeys(t *testing.T) {
-	d := &Discography{
-		Tracks: []Track{
-			{Name: "Song A", Year: "2000", Orchestra: "Orch2"},
-			{Name: "Song B", Year: "1999", Orchestra: "Orch1"},
-			{Name: "Song C", Year: "2000", Orchestra: "Orch1"},
-			{Name: "Song D", Year: "1998", Orchestra: "Orch2"},
-		},
+func TestSplit(t *testing.T) {
+	discography, err := readFile("testplaylist.xml", "", "", nil)
+	if err != nil {
+		t.Fatal(err)
 	}
-
-	// Sort by year in ascending order
-	d.sortBy("year", "ascending")
-
-	expectedOrder := []string{"Song D", "Song B", "Song A", "Song C"}
-	for i, track := range d.Tracks {
-		if track.Name != expectedOrder[i] {
-			t.Errorf("Expected %s, but got %s", expectedOrder[i], track.Name)
+	counts := func(groups []DiscographyGroup) string {
+		var values []string
+		for _, g := range groups {
+			values = append(values, fmt.Sprintf("%s:%d", g.Value, len(g.Discography.Tracks)))
+		}
+		return strings.Join(values, ",")
+	}
+	for attr, expect := range map[string]string{
+		"genre":  "tango:8,vals:2",
+		"decade": "1950s:4,1930s:2,1940s:1,1960s:1,1920s:2",
+		"vocal":  "Jorge Durán:1,Ángel Vargas:2,Instrumental:6,Raúl Beron, Jorge Casal:1",
+	} {
+		groups, err := discography.Split(attr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := counts(groups); got != expect {
+			t.Errorf("wrong %s groups %s, expected %s", attr, got, expect)
 		}
 	}
+	if _, err := discography.Split("label"); err == nil {
+		t.Error("expected error for unsupported split attribute")
+	}
 
-	// Sort by orchestra and then by year
-	d.sortBy("orchestra,year", "ascending")
-
-	expectedOrder = []string{"Song B", "Song C", "Song D", "Song A"}
-	for i, track := range d.Tracks {
-		if track.Name != expectedOrder[i] {
-			t.Errorf("Expected %s, but got %s", expectedOrder[i], track.Name)
-		}
+	opts := Options{File: "testplaylist.xml", SplitBy: "genre"}
+	specs, err := opts.playlists(discography)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 2 || specs[0].Title != "testplaylist - tango" || specs[1].Title != "testplaylist - vals" {
+		t.Errorf("wrong split playlists %+v", specs)
+	}
+	opts.Title = "{group} ({years})"
+	specs, err = opts.playlists(discography)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if specs[0].Title != "tango (1927-1963)" || specs[1].Title != "vals (1938-1953)" {
+		t.Errorf("wrong split playlist titles %s, %s", specs[0].Title, specs[1].Title)
+	}
+	// titles of split playlists should be distinct
+	opts.Title = "{genre}"
+	opts.SplitBy = "decade"
+	if _, err := opts.playlists(discography); err == nil {
+		t.Error("expected error for same titles of split playlists")
 	}
 }
 
-func TestFilterBy(t *testing.T) {
-	d := &Discography{
-		Tracks: []Track{
-			{Name: "Song A", Year: "2000", Orchestra: "Orch1", Genre: "Classical"},
-			{Name: "Song B", Year: "1999", Orchestra: "Orch1", Genre: "Jazz"},
-			{Name: "Song C", Year: "2000", Orchestra: "Orch2", Genre: "Classical"},
-			{Name: "Song D", Year: "1998", Orchestra: "Orch2", Genre: "Jazz"},
-		},
+func TestPlaylistParts(t *testing.T) {
+	discography, err := readFile("testplaylist.xml", "", "", nil)
+	if err != nil {
+		t.Fatal(err)
 	}
-
-	// Filter by "orchestra" = "Orch1"
-	d.filterBy(map[string]string{
-		"orchestra": "Orch1",
-	})
-
-	expectedTracks := []Track{
-		{Name: "Song A", Year: "2000", Orchestra: "Orch1", Genre: "Classical"},
-		{Name: "Song B", Year: "1999", Orchestra: "Orch1", Genre: "Jazz"},
+	discography.Tracks[4].Orchestra = ""
+	parts := playlistParts(PlaylistSpec{Title: "Cumparsitas", Discography: discography}, 4)
+	var titles []string
+	for _, p := range parts {
+		titles = append(titles, fmt.Sprintf("%s:%d", p.Title, len(p.Discography.Tracks)))
 	}
-
-	if len(d.Tracks) != len(expectedTracks) {
-		t.Fatalf("Expected %d tracks, but got %d", len(expectedTracks), len(d.Tracks))
+	if got := strings.Join(titles, ","); got != "Cumparsitas (1/3):4,Cumparsitas (2/3):4,Cumparsitas (3/3):2" {
+		t.Errorf("wrong playlist parts %s", got)
 	}
-
-	for i, track := range d.Tracks {
-		if track.Name != expectedTracks[i].Name || track.Orchestra != expectedTracks[i].Orchestra {
-			t.Errorf("Track mismatch. Expected %+v, but got %+v", expectedTracks[i], track)
-		}
+	// the second part starts with track without orchestra which inherits it from the first part
+	if tracks := playlistTracks(parts[1].Title, parts[1].Discography); tracks[0].Orchestra != "Anibal Troilo" {
+		t.Errorf("wrong orchestra of the first track of the second part %+v", tracks[0])
 	}
-
-	// Filter by "orchestra" = "Orch1" and "genre" = "Classical"
-	d = &Discography{
-		Tracks: []Track{
-			{Name: "Song A", Year: "2000", Orchestra: "Orch1", Genre: "Classical"},
-			{Name: "Song B", Year: "1999", Orchestra: "Orch1", Genre: "Jazz"},
-			{Name: "Song C", Year: "2000", Orchestra: "Orch2", Genre: "Classical"},
-			{Name: "Song D", Year: "1998", Orchestra: "Orch2", Genre: "Jazz"},
-		},
+	if parts := playlistParts(PlaylistSpec{Title: "All", Discography: discography}, 0); len(parts) != 1 || parts[0].Title != "All" {
+		t.Errorf("playlist without size limit should not be split %+v", parts)
 	}
-	d.filterBy(map[string]string{
-		"orchestra": "Orch1",
-		"genre":     "Classical",
-	})
 
-	expectedTracks = []Track{
-		{Name: "Song A", Year: "2000", Orchestra: "Orch1", Genre: "Classical"},
+	defer func() { Config = Configuration{} }()
+	if maxPlaylistSize("youtube") != 5000 || maxPlaylistSize("subsonic") != 0 {
+		t.Error("wrong default playlist size limits")
 	}
-
-	if len(d.Tracks) != len(expectedTracks) {
-		t.Fatalf("Expected %d tracks, but got %d", len(expectedTracks), len(d.Tracks))
+	Config.MaxPlaylistSize = 100
+	if maxPlaylistSize("youtube") != 100 || maxPlaylistSize("subsonic") != 100 {
+		t.Error("configuration should lower playlist size limits")
 	}
-
-	for i, track := range d.Tracks {
-		if track.Name != expectedTracks[i].Name || track.Orchestra != expectedTracks[i].Orchestra || track.Genre != expectedTracks[i].Genre {
-			t.Errorf("Track mismatch. Expected %+v, but got %+v", expectedTracks[i], track)
-		}
+	Config.MaxPlaylistSize = 20000
+	if maxPlaylistSize("spotify") != 10000 {
+		t.Error("configuration should not raise playlist size limit of the service")
 	}
 }
@@ -0,0 +1,248 @@
+package discography
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DatePrecision represents precision of the recording date
+type DatePrecision int
+
+const (
+	DateUnknown DatePrecision = iota // no date, e.g. ""
+	DateDecade                       // decade only, e.g. "194?"
+	DateYear                         // year only, e.g. "1951"
+	DateMonth                        // year and month, e.g. "1951-03"
+	DateDay                          // full date, e.g. "1951-03-02"
+)
+
+// RecordingDate represents recording date of the track, the date may have
+// partial precision, e.g. "1951", "1951-03" or "1938-04-18", or be
+// uncertain, e.g. "194?", "c.1940" or "1940-xx". The original text of the
+// date is preserved in discography files.
+type RecordingDate string
+
+// dateParts represents parsed recording date
+type dateParts struct {
+	year      int
+	month     int
+	day       int
+	precision DatePrecision
+	uncertain bool
+}
+
+// circaPattern matches prefix of approximate dates, e.g. c.1940 or circa 1940
+var circaPattern = regexp.MustCompile(`(?i)^(circa|ca\.?|c\.?|~)\s*`)
+
+// helper function to check if given string consists of digits only
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// helper function to check if given date part is unknown, e.g. xx or ??
+func isUnknownPart(s string) bool {
+	return s != "" && strings.Trim(s, "xX?") == ""
+}
+
+// helper function to parse recording date
+func (d RecordingDate) parse() (dateParts, error) {
+	var p dateParts
+	s := strings.TrimSpace(string(d))
+	if s == "" {
+		return p, nil
+	}
+	malformed := fmt.Errorf("malformed date %q, expected YYYY, YYYY-MM or YYYY-MM-DD", string(d))
+	if loc := circaPattern.FindStringIndex(s); loc != nil {
+		p.uncertain = true
+		s = s[loc[1]:]
+	}
+	// approximate year, e.g. 1940?
+	if len(s) == 5 && s[4] == '?' && isDigits(s[:4]) {
+		p.uncertain = true
+		s = s[:4]
+	}
+	parts := strings.Split(s, "-")
+	if len(parts) > 3 || len(parts[0]) != 4 {
+		return dateParts{}, malformed
+	}
+	switch year := parts[0]; {
+	case isDigits(year):
+		p.year, _ = strconv.Atoi(year)
+		p.precision = DateYear
+	case isDigits(year[:3]) && isUnknownPart(year[3:]):
+		p.year, _ = strconv.Atoi(year[:3])
+		p.year *= 10
+		p.precision = DateDecade
+		p.uncertain = true
+	default:
+		return dateParts{}, malformed
+	}
+	var unknown bool
+	for _, part := range parts[1:] {
+		if isUnknownPart(part) {
+			p.uncertain = true
+			unknown = true
+			continue
+		}
+		// known month or day can't follow unknown parts, e.g. 194?-03
+		if unknown || p.precision < DateYear || len(part) != 2 || !isDigits(part) {
+			return dateParts{}, malformed
+		}
+		value, _ := strconv.Atoi(part)
+		if p.precision == DateYear {
+			p.month = value
+		} else {
+			p.day = value
+		}
+		p.precision++
+	}
+	if p.month != 0 && (p.month < 1 || p.month > 12) {
+		return dateParts{}, fmt.Errorf("impossible date %q", string(d))
+	}
+	if p.precision == DateDay {
+		t := time.Date(p.year, time.Month(p.month), p.day, 0, 0, 0, 0, time.UTC)
+		if t.Day() != p.day {
+			return dateParts{}, fmt.Errorf("impossible date %q", string(d))
+		}
+	}
+	return p, nil
+}
+
+// Validate checks that recording date can be parsed, empty date is valid
+func (d RecordingDate) Validate() error {
+	_, err := d.parse()
+	return err
+}
+
+// Precision returns precision of the date, malformed date has unknown precision
+func (d RecordingDate) Precision() DatePrecision {
+	p, _ := d.parse()
+	return p.precision
+}
+
+// Uncertain returns true if the date is approximate, e.g. "c.1940" or "194?"
+func (d RecordingDate) Uncertain() bool {
+	p, _ := d.parse()
+	return p.uncertain
+}
+
+// Year returns year of the date, the first year of the decade for dates
+// known up to decade and 0 if date is unknown
+func (d RecordingDate) Year() int {
+	p, _ := d.parse()
+	return p.year
+}
+
+// Decade returns decade of the date, e.g. 1940, or 0 if date is unknown
+func (d RecordingDate) Decade() int {
+	return d.Year() / 10 * 10
+}
+
+// YearRange returns range of years the date belongs to, e.g. 1940-1949 for
+// "194?" and 1951-1951 for "1951-03-02"
+func (d RecordingDate) YearRange() (int, int) {
+	p, _ := d.parse()
+	switch p.precision {
+	case DateUnknown:
+		return 0, 0
+	case DateDecade:
+		return p.year, p.year + 9
+	}
+	return p.year, p.year
+}
+
+// YearString returns year of the date if it is known, e.g. "1951", otherwise
+// it returns empty string, it is used to build search queries and to match
+// recordings of services
+func (d RecordingDate) YearString() string {
+	p, _ := d.parse()
+	if p.precision < DateYear {
+		return ""
+	}
+	return fmt.Sprintf("%04d", p.year)
+}
+
+// Key returns canonical form of the date used to compare dates, e.g.
+// "1940-xx" and "1940" have the same key, malformed dates are kept as is
+func (d RecordingDate) Key() string {
+	p, err := d.parse()
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(string(d)))
+	}
+	switch p.precision {
+	case DateDecade:
+		return fmt.Sprintf("%03dx", p.year/10)
+	case DateYear:
+		return fmt.Sprintf("%04d", p.year)
+	case DateMonth:
+		return fmt.Sprintf("%04d-%02d", p.year, p.month)
+	case DateDay:
+		return fmt.Sprintf("%04d-%02d-%02d", p.year, p.month, p.day)
+	}
+	return ""
+}
+
+// SortKey returns key to sort dates chronologically, less precise dates
+// come before more precise ones of the same period and unknown dates come first
+func (d RecordingDate) SortKey() string {
+	p, err := d.parse()
+	if err != nil || p.precision == DateUnknown {
+		return ""
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", p.year, p.month, p.day)
+}
+
+// yearRangePattern matches range of years, e.g. 1938-1942
+var yearRangePattern = regexp.MustCompile(`^(\d{4})\s*-\s*(\d{4})$`)
+
+// decadePattern matches decade, e.g. 1940s
+var decadePattern = regexp.MustCompile(`^(\d{3})0s$`)
+
+// Matches checks if the date matches given condition:
+// decade, e.g. "1940s", date belongs to the decade
+// range of years, e.g. "1938-1942", date belongs to the range
+// date, e.g. "1951" or "1951-03", date is within given period
+// otherwise the condition is used as regular expression of the date text
+// or its year
+func (d RecordingDate) Matches(cond string) bool {
+	cond = strings.TrimSpace(cond)
+	from, to := d.YearRange()
+	if m := decadePattern.FindStringSubmatch(cond); m != nil {
+		decade, _ := strconv.Atoi(m[1])
+		return from != 0 && from >= decade*10 && to <= decade*10+9
+	}
+	if m := yearRangePattern.FindStringSubmatch(cond); m != nil {
+		start, _ := strconv.Atoi(m[1])
+		end, _ := strconv.Atoi(m[2])
+		return from != 0 && from >= start && to <= end
+	}
+	if c, err := RecordingDate(cond).parse(); err == nil && c.precision >= DateYear && !c.uncertain {
+		p, _ := d.parse()
+		if p.precision < c.precision {
+			return false
+		}
+		return p.year == c.year &&
+			(c.precision < DateMonth || p.month == c.month) &&
+			(c.precision < DateDay || p.day == c.day)
+	}
+	re, err := regexp.Compile("(?i)^" + cond + "$")
+	if err != nil {
+		return strings.EqualFold(string(d), cond)
+	}
+	if re.MatchString(string(d)) {
+		return true
+	}
+	year := d.YearString()
+	return year != "" && re.MatchString(year)
+}
@@ -0,0 +1,108 @@
+package discography
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestRecordingDate(t *testing.T) {
+	tests := []struct {
+		date      RecordingDate
+		precision DatePrecision
+		uncertain bool
+		year      string
+		key       string
+		from, to  int
+	}{
+		{"", DateUnknown, false, "", "", 0, 0},
+		{"1951", DateYear, false, "1951", "1951", 1951, 1951},
+		{"1951-03", DateMonth, false, "1951", "1951-03", 1951, 1951},
+		{"1938-04-18", DateDay, false, "1938", "1938-04-18", 1938, 1938},
+		{"194?", DateDecade, true, "", "194x", 1940, 1949},
+		{"c.1940", DateYear, true, "1940", "1940", 1940, 1940},
+		{"circa 1940", DateYear, true, "1940", "1940", 1940, 1940},
+		{"1940?", DateYear, true, "1940", "1940", 1940, 1940},
+		{"1940-xx", DateYear, true, "1940", "1940", 1940, 1940},
+		{"1940-03-XX", DateMonth, true, "1940", "1940-03", 1940, 1940},
+	}
+	for _, tt := range tests {
+		if err := tt.date.Validate(); err != nil {
+			t.Errorf("%q: unexpected error %v", tt.date, err)
+		}
+		if p := tt.date.Precision(); p != tt.precision {
+			t.Errorf("%q: expected precision %d, got %d", tt.date, tt.precision, p)
+		}
+		if u := tt.date.Uncertain(); u != tt.uncertain {
+			t.Errorf("%q: expected uncertain %v, got %v", tt.date, tt.uncertain, u)
+		}
+		if y := tt.date.YearString(); y != tt.year {
+			t.Errorf("%q: expected year %q, got %q", tt.date, tt.year, y)
+		}
+		if k := tt.date.Key(); k != tt.key {
+			t.Errorf("%q: expected key %q, got %q", tt.date, tt.key, k)
+		}
+		if from, to := tt.date.YearRange(); from != tt.from || to != tt.to {
+			t.Errorf("%q: expected range %d-%d, got %d-%d", tt.date, tt.from, tt.to, from, to)
+		}
+	}
+
+	for _, date := range []RecordingDate{"19x1", "1951-02-30", "1951-13", "194?-03", "1940-xx-02", "51", "1951-3-2"} {
+		if err := date.Validate(); err == nil {
+			t.Errorf("%q: expected error", date)
+		}
+	}
+}
+
+func TestRecordingDateMatches(t *testing.T) {
+	tests := []struct {
+		date  RecordingDate
+		cond  string
+		match bool
+	}{
+		{"1951-03-02", "1951", true},
+		{"1951-03-02", "1951-03", true},
+		{"1951-03-02", "1951-04", false},
+		{"1951", "1951-03", false},
+		{"1951-03-02", "1950s", true},
+		{"194?", "1940s", true},
+		{"194?", "1940", false},
+		{"194?", "1938-1945", false},
+		{"c.1940", "1938-1945", true},
+		{"1937", "193[0-9]", true},
+		{"1947", "193[0-9]", false},
+		{"1938-04-18", "193[0-9]", true},
+		{"1948-04-18", "193[0-9]", false},
+		{"", "1950s", false},
+	}
+	for _, tt := range tests {
+		if m := tt.date.Matches(tt.cond); m != tt.match {
+			t.Errorf("%q matches %q: expected %v, got %v", tt.date, tt.cond, tt.match, m)
+		}
+	}
+}
+
+func TestFilterByDate(t *testing.T) {
+	d := &Discography{
+		Tracks: []Track{
+			{Name: "Song A", Year: "1938-04-18"},
+			{Name: "Song B", Year: "194?"},
+			{Name: "Song C", Year: "c.1944"},
+			{Name: "Song D", Year: "1951"},
+		},
+	}
+	d.FilterBy(map[string]string{"decade": "1940"})
+	if len(d.Tracks) != 2 || d.Tracks[0].Name != "Song B" || d.Tracks[1].Name != "Song C" {
+		t.Errorf("unexpected tracks of the decade: %+v", d.Tracks)
+	}
+}
+
+func TestRecordingDateSort(t *testing.T) {
+	dates := []RecordingDate{"1951-03-02", "194?", "", "1951", "1940-05", "c.1939"}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].SortKey() < dates[j].SortKey() })
+	expect := []RecordingDate{"", "c.1939", "194?", "1940-05", "1951", "1951-03-02"}
+	for i := range dates {
+		if dates[i] != expect[i] {
+			t.Fatalf("expected order %v, got %v", expect, dates)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+// Package discography provides model of tango discographies, i.e. tracks
+// of orchestras along with their recording dates, genres and vocalists, and
+// functions to read, write, filter, sort and deduplicate them. It is shared
+// by goplaylist and its sister tools.
+package discography
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vkuznet/goplaylist/normalize"
+)
+
+// Discography represents discography object
+type Discography struct {
+	Orchestra string  `xml:"orchestra,attr"`
+	Tracks    []Track `xml:"track"`
+}
+
+// SortBy sorts the tracks of the Discography by the specified attribute and order.
+// Supported attributes: "orchestra", "year", "name", "artist", "genre", "vocal",
+// "composer", "author", "label", "matrix", "duration", "isrc" and extra
+// attributes provided by the tracks, e.g. "rating" or "bpm".
+// Order can be "ascending" or "descending".
+func (d *Discography) SortBy(keys, order string) {
+	sortKeys := strings.Split(keys, ",")
+	if len(sortKeys) < 1 || len(sortKeys) > 2 {
+		fmt.Println("Invalid number of keys. Provide one or two keys.")
+		return
+	}
+
+	for _, key := range sortKeys {
+		if _, ok := SortValue(Track{}, key); !ok && !d.hasExtra(key) {
+			fmt.Printf("Unsupported sort key: %s\n", key)
+			return
+		}
+	}
+
+	sort.SliceStable(d.Tracks, func(i, j int) bool {
+		less := func(val1, val2 string) bool {
+			if order == "descending" {
+				return val1 > val2
+			}
+			return val1 < val2
+		}
+		for _, key := range sortKeys {
+			val1, _ := SortValue(d.Tracks[i], key)
+			val2, _ := SortValue(d.Tracks[j], key)
+			if val1 != val2 {
+				return less(val1, val2)
+			}
+		}
+		return false
+	})
+}
+
+// SortValue provides value of track attribute used in sorting, the
+// names are compared after normalization, i.e. case, diacritics and known
+// aliases of orchestras and artists are ignored
+func SortValue(track Track, key string) (string, bool) {
+	switch key {
+	case "orchestra":
+		return normalize.OrchestraKey(track.Orchestra), true
+	case "year":
+		return track.Year.SortKey(), true
+	case "name":
+		return normalize.Key(track.Name), true
+	case "artist":
+		return normalize.Key(normalize.DefaultResolver.Artist(track.Artist)), true
+	case "genre":
+		return normalize.Key(track.Genre), true
+	case "vocal":
+		return normalize.Key(normalize.DefaultResolver.Artist(track.Vocal)), true
+	case "composer", "author":
+		value, _ := track.Attr(key)
+		return normalize.Key(normalize.DefaultResolver.Artist(value)), true
+	case "label", "matrix":
+		value, _ := track.Attr(key)
+		return normalize.Key(value), true
+	case "isrc":
+		return NormalizeISRC(track.ISRC), true
+	case "duration":
+		return fmt.Sprintf("%06d", DurationSeconds(track.Duration)), true
+	}
+	value, ok := track.Extra[key]
+	return extraSortValue(value), ok
+}
+
+// helper function to get sort value of extra attribute, numbers, e.g. bpm
+// or rating, are compared by their values
+func extraSortValue(value string) string {
+	if f, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && f >= 0 {
+		return fmt.Sprintf("%020.6f", f)
+	}
+	return normalize.Key(value)
+}
+
+// helper function to check if any track of the discography provides
+// extra attribute with given name
+func (d *Discography) hasExtra(name string) bool {
+	for _, track := range d.Tracks {
+		if _, ok := track.Extra[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterBy keeps tracks which match all given filters, supported keys are
+// track attributes along with "decade" and extra attributes provided by the
+// tracks, names are compared after normalization
+func (d *Discography) FilterBy(filters map[string]string) {
+	var filteredTracks []Track
+
+	// extra attributes are supported only if tracks provide them
+	extras := make(map[string]bool)
+	for key := range filters {
+		extras[key] = d.hasExtra(key)
+	}
+
+	for _, track := range d.Tracks {
+		match := true
+		var matches []bool
+
+		// Check all filters against the track
+		var keys []string
+		for key, value := range filters {
+			keys = append(keys, key)
+			switch key {
+			case "orchestra":
+				if normalize.OrchestraKey(track.Orchestra) != normalize.OrchestraKey(value) {
+					match = false
+				}
+			case "year":
+				// year, date, range of years, decade or regex of the date
+				if !track.Year.Matches(value) {
+					match = false
+				}
+			case "decade":
+				if !track.Year.Matches(strings.TrimSuffix(value, "s") + "s") {
+					match = false
+				}
+			case "name":
+				if !normalize.Equal(track.Name, value) {
+					match = false
+				}
+			case "artist":
+				if !normalize.Equal(normalize.DefaultResolver.Artist(track.Artist), normalize.DefaultResolver.Artist(value)) {
+					match = false
+				}
+			case "genre":
+				if !normalize.Equal(track.Genre, value) {
+					match = false
+				}
+			case "vocal", "composer", "author":
+				trackValue, _ := track.Attr(key)
+				if !normalize.Equal(normalize.DefaultResolver.Artist(trackValue), normalize.DefaultResolver.Artist(value)) {
+					match = false
+				}
+			case "label", "matrix":
+				trackValue, _ := track.Attr(key)
+				if !normalize.Equal(trackValue, value) {
+					match = false
+				}
+			case "isrc":
+				if NormalizeISRC(track.ISRC) != NormalizeISRC(value) {
+					match = false
+				}
+			default:
+				if !extras[key] {
+					fmt.Printf("Unsupported filter key: %s\n", key)
+					match = false
+				} else if !normalize.Equal(track.Extra[key], value) {
+					match = false
+				}
+			}
+
+			if match {
+				matches = append(matches, match)
+			}
+
+			// Stop checking further if any condition fails
+			//             if !match {
+			//                 break
+			//             }
+		}
+
+		// Add the track to the filtered list if it matches all conditions
+		if len(matches) == len(keys) {
+			filteredTracks = append(filteredTracks, track)
+		}
+	}
+
+	// Replace the original tracks with the filtered ones
+	d.Tracks = filteredTracks
+}
+
+// DedupePolicies lists supported policies of duplicate tracks removal:
+// exact       - all track attributes are the same
+// recording   - the same recording, i.e. orchestra, full date, title and vocal
+// title       - the same title of the orchestra, e.g. one version of each tango
+// none        - keep all tracks
+var DedupePolicies = []string{"exact", "recording", "title", "none"}
+
+// DroppedTrack represents duplicate track removed from discography along
+// with the track it was merged into
+type DroppedTrack struct {
+	Track      Track
+	MergedInto Track
+}
+
+// DedupeKey provides deduplication key of the track for given policy,
+// all attributes are compared after normalization
+func (t *Track) DedupeKey(policy string) (string, error) {
+	orchestra := normalize.OrchestraKey(t.Orchestra)
+	name := normalize.Key(t.Name)
+	switch policy {
+	case "exact":
+		return fmt.Sprintf("%s|%s|%s|%s|%s|%s", orchestra, t.Year.Key(), name,
+			normalize.Key(t.Artist), normalize.Key(t.Genre), normalize.Key(t.Vocal)), nil
+	case "recording":
+		return fmt.Sprintf("%s|%s|%s|%s", orchestra, t.Year.Key(), name, normalize.Key(t.Vocal)), nil
+	case "title":
+		return fmt.Sprintf("%s|%s", orchestra, name), nil
+	}
+	return "", fmt.Errorf("unsupported dedupe policy '%s', supported policies: %s",
+		policy, strings.Join(DedupePolicies, ", "))
+}
+
+// helper function to measure completeness of the track record, i.e. number
+// of provided attributes and precision of its date
+func (t *Track) completeness() int {
+	var score int
+	for _, value := range []string{t.Orchestra, t.Name, t.Artist, t.Genre, t.Vocal} {
+		if strings.TrimSpace(value) != "" {
+			score++
+		}
+	}
+	return score + int(t.Year.Precision())
+}
+
+// helper function to fill attributes of the track which are missing in it
+// from another record of the same recording, present attributes are kept
+func (t *Track) merge(other Track) {
+	for _, attr := range []struct{ dst, src *string }{
+		{&t.Artist, &other.Artist}, {&t.Genre, &other.Genre}, {&t.Vocal, &other.Vocal},
+		{&t.Composer, &other.Composer}, {&t.Author, &other.Author}, {&t.Label, &other.Label},
+		{&t.Matrix, &other.Matrix}, {&t.Duration, &other.Duration}, {&t.ISRC, &other.ISRC},
+		{&t.SpotifyID, &other.SpotifyID}, {&t.YoutubeID, &other.YoutubeID},
+	} {
+		if strings.TrimSpace(*attr.dst) == "" {
+			*attr.dst = *attr.src
+		}
+	}
+	if len(other.Extra) == 0 {
+		return
+	}
+	// new map is used since extra attributes are shared with original track
+	extra := make(map[string]string, len(t.Extra)+len(other.Extra))
+	for key, value := range other.Extra {
+		extra[key] = value
+	}
+	for key, value := range t.Extra {
+		extra[key] = value
+	}
+	t.Extra = extra
+}
+
+// RemoveDuplicates removes duplicate tracks of the discography according
+// to given policy, duplicates are merged into the most complete record which
+// takes place of the first one, it returns list of dropped tracks. Records of
+// the same recording (exact and recording policies) also fill attributes
+// missing in the kept record, e.g. label or matrix, while different
+// recordings of the title policy are not mixed.
+func (d *Discography) RemoveDuplicates(policy string) ([]DroppedTrack, error) {
+	if policy == "" || policy == "none" {
+		return nil, nil
+	}
+	var keys []string
+	groups := make(map[string][]Track)
+	for _, track := range d.Tracks {
+		key, err := track.DedupeKey(policy)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], track)
+	}
+
+	var dropped []DroppedTrack
+	uniqueTracks := []Track{}
+	for _, key := range keys {
+		tracks := groups[key]
+		best := 0
+		for idx, track := range tracks {
+			if track.completeness() > tracks[best].completeness() {
+				best = idx
+			}
+		}
+		kept := tracks[best]
+		if policy != "title" {
+			for idx, track := range tracks {
+				if idx != best {
+					kept.merge(track)
+				}
+			}
+		}
+		for idx, track := range tracks {
+			if idx != best {
+				dropped = append(dropped, DroppedTrack{Track: track, MergedInto: kept})
+			}
+		}
+		uniqueTracks = append(uniqueTracks, kept)
+	}
+	d.Tracks = uniqueTracks
+	return dropped, nil
+}
+
+// Group represents group of discography tracks sharing the same
+// value of an attribute
+type Group struct {
+	Value       string
+	Discography *Discography
+}
+
+// SplitValue provides value of track attribute used to split the
+// discography, supported attributes: genre, vocal, orchestra and decade
+func (d *Discography) SplitValue(track Track, attr string) (string, error) {
+	var value string
+	switch attr {
+	case "genre":
+		value = track.Genre
+	case "vocal":
+		value = track.Vocal
+	case "orchestra":
+		value = track.Orchestra
+		if value == "" {
+			value = d.Orchestra
+		}
+		value = normalize.Orchestra(value)
+	case "decade":
+		if track.Year.Precision() != DateUnknown {
+			value = fmt.Sprintf("%ds", track.Year.Decade())
+		}
+	default:
+		return "", fmt.Errorf("unsupported split attribute '%s', should be genre, vocal, orchestra or decade", attr)
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		value = "unknown"
+	}
+	return value, nil
+}
+
+// Split splits discography into groups of tracks with the same
+// value of given attribute, the values are compared after normalization and
+// groups follow order of their first track in the discography
+func (d *Discography) Split(attr string) ([]Group, error) {
+	var groups []Group
+	index := make(map[string]int)
+	for _, track := range d.Tracks {
+		value, err := d.SplitValue(track, attr)
+		if err != nil {
+			return nil, err
+		}
+		key := normalize.Key(value)
+		idx, ok := index[key]
+		if !ok {
+			idx = len(groups)
+			index[key] = idx
+			groups = append(groups, Group{
+				Value:       value,
+				Discography: &Discography{Orchestra: d.Orchestra},
+			})
+		}
+		groups[idx].Discography.Tracks = append(groups[idx].Discography.Tracks, track)
+	}
+	return groups, nil
+}
+
+// helper function to resolve artist names separated by comma, e.g.
+// "Raúl Berón, Jorge Casal"
+func resolveArtists(r *normalize.Resolver, names string) string {
+	if names == "" {
+		return names
+	}
+	var result []string
+	for _, name := range strings.Split(names, ",") {
+		result = append(result, r.Artist(strings.TrimSpace(name)))
+	}
+	return strings.Join(result, ", ")
+}
+
+// ResolveAliases replaces known aliases of orchestras and artists of the
+// discography with their canonical names registered in given resolver
+func (d *Discography) ResolveAliases(r *normalize.Resolver) {
+	if d.Orchestra != "" {
+		d.Orchestra = r.OrchestraAlias(d.Orchestra)
+	}
+	for i, track := range d.Tracks {
+		if track.Orchestra != "" {
+			d.Tracks[i].Orchestra = r.OrchestraAlias(track.Orchestra)
+		}
+		d.Tracks[i].Vocal = resolveArtists(r, track.Vocal)
+		d.Tracks[i].Artist = resolveArtists(r, track.Artist)
+	}
+}
@@ -0,0 +1,450 @@
+package discography
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteAndReadXMLFile(t *testing.T) {
+	xmlFilename := "test.xml"
+
+	expectedDiscography := &Discography{
+		Orchestra: "Ricardo Tanturi",
+		Tracks: []Track{
+			{Name: "Una noche más", Orchestra: "Ricardo Tanturi", Year: "1941"},
+			{Name: "En el salón", Orchestra: "Ricardo Tanturi", Year: "1943"},
+		},
+	}
+
+	// Write XML file
+	file, err := os.Create(xmlFilename)
+	if err != nil {
+		t.Fatalf("Failed to create XML file: %v", err)
+	}
+	defer os.Remove(xmlFilename) // Cleanup after the test
+	defer file.Close()
+
+	// Write the XML content to the file
+	encoder := xml.NewEncoder(file)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(expectedDiscography); err != nil {
+		t.Fatalf("Failed to write XML to file: %v", err)
+	}
+
+	// Read XML file back
+	discography, err := ReadXMLFile(xmlFilename)
+	if err != nil {
+		t.Fatalf("Failed to read XML file: %v", err)
+	}
+
+	// Compare the expected and actual results
+	if !reflect.DeepEqual(discography, expectedDiscography) {
+		t.Errorf("Expected %+v, got %+v", expectedDiscography, discography)
+	}
+}
+
+func TestWriteAndReadCSV(t *testing.T) {
+	csvFilename := "test.csv"
+	expectedDiscography := &Discography{
+		Tracks: []Track{
+			{Name: "Una noche más", Orchestra: "Ricardo Tanturi", Year: "1941"},
+			{Name: "En el salón", Orchestra: "Ricardo Tanturi", Year: "1943"},
+		},
+	}
+
+	// Write CSV
+	csvFile, err := os.Create(csvFilename)
+	if err != nil {
+		t.Fatalf("Failed to create CSV file: %v", err)
+	}
+	defer os.Remove(csvFilename)
+	defer csvFile.Close()
+
+	for _, track := range expectedDiscography.Tracks {
+		_, err := csvFile.WriteString(track.Orchestra + "," + string(track.Year) + "," + track.Name + "\n")
+		if err != nil {
+			t.Fatalf("Failed to write to CSV file: %v", err)
+		}
+	}
+
+	csvFile.Close()
+
+	// Read CSV
+	discography, err := ReadCSVFile(csvFilename)
+	if err != nil {
+		t.Fatalf("Failed to read CSV file: %v", err)
+	}
+
+	if !reflect.DeepEqual(discography, expectedDiscography) {
+		t.Errorf("Expected %+v, got %+v", expectedDiscography, discography)
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	// Create a sample Discography object
+	discography := Discography{
+		Orchestra: "Orchestra1",
+		Tracks: []Track{
+			{Orchestra: "Orchestra2", Year: "2020", Name: "Track C", Artist: "Artist A", Genre: "Genre X", Vocal: "Vocal X"},
+			{Orchestra: "Orchestra3", Year: "2019", Name: "Track A", Artist: "Artist B", Genre: "Genre Z", Vocal: "Vocal Y"},
+			{Orchestra: "Orchestra1", Year: "2021", Name: "Track B", Artist: "Artist A", Genre: "Genre Y", Vocal: "Vocal Z"},
+		},
+	}
+
+	tests := []struct {
+		attr        string
+		order       string
+		expected    []Track
+		description string
+	}{
+		{
+			attr:  "year",
+			order: "ascending",
+			expected: []Track{
+				{Orchestra: "Orchestra3", Year: "2019", Name: "Track A", Artist: "Artist B", Genre: "Genre Z", Vocal: "Vocal Y"},
+				{Orchestra: "Orchestra2", Year: "2020", Name: "Track C", Artist: "Artist A", Genre: "Genre X", Vocal: "Vocal X"},
+				{Orchestra: "Orchestra1", Year: "2021", Name: "Track B", Artist: "Artist A", Genre: "Genre Y", Vocal: "Vocal Z"},
+			},
+			description: "Sort by year in ascending order",
+		},
+		{
+			attr:  "name",
+			order: "descending",
+			expected: []Track{
+				{Orchestra: "Orchestra2", Year: "2020", Name: "Track C", Artist: "Artist A", Genre: "Genre X", Vocal: "Vocal X"},
+				{Orchestra: "Orchestra1", Year: "2021", Name: "Track B", Artist: "Artist A", Genre: "Genre Y", Vocal: "Vocal Z"},
+				{Orchestra: "Orchestra3", Year: "2019", Name: "Track A", Artist: "Artist B", Genre: "Genre Z", Vocal: "Vocal Y"},
+			},
+			description: "Sort by name in descending order",
+		},
+		{
+			attr:  "artist",
+			order: "ascending",
+			expected: []Track{
+				{Orchestra: "Orchestra2", Year: "2020", Name: "Track C", Artist: "Artist A", Genre: "Genre X", Vocal: "Vocal X"},
+				{Orchestra: "Orchestra1", Year: "2021", Name: "Track B", Artist: "Artist A", Genre: "Genre Y", Vocal: "Vocal Z"},
+				{Orchestra: "Orchestra3", Year: "2019", Name: "Track A", Artist: "Artist B", Genre: "Genre Z", Vocal: "Vocal Y"},
+			},
+			description: "Sort by artist in ascending order",
+		},
+		{
+			attr:  "genre",
+			order: "ascending",
+			expected: []Track{
+				{Orchestra: "Orchestra2", Year: "2020", Name: "Track C", Artist: "Artist A", Genre: "Genre X", Vocal: "Vocal X"},
+				{Orchestra: "Orchestra1", Year: "2021", Name: "Track B", Artist: "Artist A", Genre: "Genre Y", Vocal: "Vocal Z"},
+				{Orchestra: "Orchestra3", Year: "2019", Name: "Track A", Artist: "Artist B", Genre: "Genre Z", Vocal: "Vocal Y"},
+			},
+			description: "Sort by genre in ascending order",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			// Make a copy of the original discography to sort
+			d := discography
+			d.SortBy(test.attr, test.order)
+
+			// Verify the result matches the expected order
+			if !reflect.DeepEqual(d.Tracks, test.expected) {
+				t.Errorf("Failed %s\nGot: %+v\nExpected: %+v", test.description, d.Tracks, test.expected)
+			}
+		})
+	}
+}
+
+func TestSortByMultipleKeys(t *testing.T) {
+	d := &Discography{
+		Tracks: []Track{
+			{Name: "Song A", Year: "2000", Orchestra: "Orch2"},
+			{Name: "Song B", Year: "1999", Orchestra: "Orch1"},
+			{Name: "Song C", Year: "2000", Orchestra: "Orch1"},
+			{Name: "Song D", Year: "1998", Orchestra: "Orch2"},
+		},
+	}
+
+	// Sort by year in ascending order
+	d.SortBy("year", "ascending")
+
+	expectedOrder := []string{"Song D", "Song B", "Song A", "Song C"}
+	for i, track := range d.Tracks {
+		if track.Name != expectedOrder[i] {
+			t.Errorf("Expected %s, but got %s", expectedOrder[i], track.Name)
+		}
+	}
+
+	// Sort by orchestra and then by year
+	d.SortBy("orchestra,year", "ascending")
+
+	expectedOrder = []string{"Song B", "Song C", "Song D", "Song A"}
+	for i, track := range d.Tracks {
+		if track.Name != expectedOrder[i] {
+			t.Errorf("Expected %s, but got %s", expectedOrder[i], track.Name)
+		}
+	}
+}
+
+func TestFilterBy(t *testing.T) {
+	d := &Discography{
+		Tracks: []Track{
+			{Name: "Song A", Year: "2000", Orchestra: "Orch1", Genre: "Classical"},
+			{Name: "Song B", Year: "1999", Orchestra: "Orch1", Genre: "Jazz"},
+			{Name: "Song C", Year: "2000", Orchestra: "Orch2", Genre: "Classical"},
+			{Name: "Song D", Year: "1998", Orchestra: "Orch2", Genre: "Jazz"},
+		},
+	}
+
+	// Filter by "orchestra" = "Orch1"
+	d.FilterBy(map[string]string{
+		"orchestra": "Orch1",
+	})
+
+	expectedTracks := []Track{
+		{Name: "Song A", Year: "2000", Orchestra: "Orch1", Genre: "Classical"},
+		{Name: "Song B", Year: "1999", Orchestra: "Orch1", Genre: "Jazz"},
+	}
+
+	if len(d.Tracks) != len(expectedTracks) {
+		t.Fatalf("Expected %d tracks, but got %d", len(expectedTracks), len(d.Tracks))
+	}
+
+	for i, track := range d.Tracks {
+		if track.Name != expectedTracks[i].Name || track.Orchestra != expectedTracks[i].Orchestra {
+			t.Errorf("Track mismatch. Expected %+v, but got %+v", expectedTracks[i], track)
+		}
+	}
+
+	// Filter by "orchestra" = "Orch1" and "genre" = "Classical"
+	d = &Discography{
+		Tracks: []Track{
+			{Name: "Song A", Year: "2000", Orchestra: "Orch1", Genre: "Classical"},
+			{Name: "Song B", Year: "1999", Orchestra: "Orch1", Genre: "Jazz"},
+			{Name: "Song C", Year: "2000", Orchestra: "Orch2", Genre: "Classical"},
+			{Name: "Song D", Year: "1998", Orchestra: "Orch2", Genre: "Jazz"},
+		},
+	}
+	d.FilterBy(map[string]string{
+		"orchestra": "Orch1",
+		"genre":     "Classical",
+	})
+
+	expectedTracks = []Track{
+		{Name: "Song A", Year: "2000", Orchestra: "Orch1", Genre: "Classical"},
+	}
+
+	if len(d.Tracks) != len(expectedTracks) {
+		t.Fatalf("Expected %d tracks, but got %d", len(expectedTracks), len(d.Tracks))
+	}
+
+	for i, track := range d.Tracks {
+		if track.Name != expectedTracks[i].Name || track.Orchestra != expectedTracks[i].Orchestra || track.Genre != expectedTracks[i].Genre {
+			t.Errorf("Track mismatch. Expected %+v, but got %+v", expectedTracks[i], track)
+		}
+	}
+
+	// Filter values are compared after normalization
+	d = &Discography{
+		Tracks: []Track{
+			{Name: "Sin rumbo fijo", Orchestra: "Orquesta Tipica Victor", Vocal: "Ángel Vargas"},
+			{Name: "La cumparsita", Orchestra: "Angel D'Agostino", Vocal: "Angel Vargas"},
+			{Name: "A la luz del candil", Orchestra: "Carlos Di Sarli", Vocal: "Jorge Durán"},
+		},
+	}
+	d.FilterBy(map[string]string{"vocal": "angel vargas"})
+	if len(d.Tracks) != 2 {
+		t.Errorf("Expected 2 tracks with normalized vocal, but got %+v", d.Tracks)
+	}
+	d.FilterBy(map[string]string{"orchestra": "OTV"})
+	if len(d.Tracks) != 1 || d.Tracks[0].Name != "Sin rumbo fijo" {
+		t.Errorf("Expected track of orchestra alias, but got %+v", d.Tracks)
+	}
+}
+
+func TestRemoveDuplicateTracks(t *testing.T) {
+	tracks := []Track{
+		{Name: "Bahía Blanca", Orchestra: "Carlos Di Sarli", Year: "1951-03-02", Genre: "Tango", Vocal: "Jorge Durán"},
+		{Name: "Bahia blanca", Orchestra: "carlos di sarli", Year: "1951-03-02", Vocal: "jorge duran"},
+		{Name: "Bahía Blanca", Orchestra: "Carlos Di Sarli", Year: "1951-11-21", Genre: "Tango", Vocal: "Oscar Serpa"},
+		{Name: "Bahía Blanca", Orchestra: "Carlos Di Sarli", Year: "1957-11-13", Genre: "Tango"},
+		{Name: "Bahía Blanca", Orchestra: "Carlos Di Sarli", Year: "1957-11-13", Genre: "Tango"},
+	}
+	tests := []struct {
+		policy  string
+		kept    int
+		dropped int
+	}{
+		{"none", 5, 0},
+		{"exact", 4, 1},
+		{"recording", 3, 2},
+		{"title", 1, 4},
+	}
+	for _, tt := range tests {
+		d := &Discography{Tracks: append([]Track{}, tracks...)}
+		dropped, err := d.RemoveDuplicates(tt.policy)
+		if err != nil {
+			t.Fatalf("policy %s: unexpected error %v", tt.policy, err)
+		}
+		if len(d.Tracks) != tt.kept || len(dropped) != tt.dropped {
+			t.Errorf("policy %s: expected %d kept and %d dropped tracks, got %d and %d",
+				tt.policy, tt.kept, tt.dropped, len(d.Tracks), len(dropped))
+		}
+		// the most complete record is kept at the place of the first one
+		if !reflect.DeepEqual(d.Tracks[0], tracks[0]) {
+			t.Errorf("policy %s: expected first track %+v, got %+v", tt.policy, tracks[0], d.Tracks[0])
+		}
+	}
+
+	// near-duplicate is merged into the most complete record
+	d := &Discography{Tracks: []Track{tracks[1], tracks[0]}}
+	dropped, _ := d.RemoveDuplicates("recording")
+	if len(dropped) != 1 || !reflect.DeepEqual(dropped[0], DroppedTrack{Track: tracks[1], MergedInto: tracks[0]}) {
+		t.Errorf("unexpected dropped tracks %+v", dropped)
+	}
+	if !reflect.DeepEqual(d.Tracks[0], tracks[0]) {
+		t.Errorf("expected the most complete record, got %+v", d.Tracks[0])
+	}
+
+	// records of the same recording complete each other
+	first := Track{Name: "Bahía Blanca", Orchestra: "Carlos Di Sarli", Year: "1957-11-13", Genre: "Tango",
+		Label: "RCA Victor", Extra: map[string]string{"bpm": "120"}}
+	second := Track{Name: "Bahia Blanca", Orchestra: "Carlos Di Sarli", Year: "1957-11-13",
+		Matrix: "E2VB-1234", Extra: map[string]string{"bpm": "118", "rating": "5"}}
+	d = &Discography{Tracks: []Track{first, second}}
+	dropped, _ = d.RemoveDuplicates("recording")
+	merged := Track{Name: "Bahía Blanca", Orchestra: "Carlos Di Sarli", Year: "1957-11-13", Genre: "Tango",
+		Label: "RCA Victor", Matrix: "E2VB-1234", Extra: map[string]string{"bpm": "120", "rating": "5"}}
+	if len(d.Tracks) != 1 || !reflect.DeepEqual(d.Tracks[0], merged) {
+		t.Errorf("expected merged record %+v, got %+v", merged, d.Tracks)
+	}
+	if len(dropped) != 1 || !reflect.DeepEqual(dropped[0].MergedInto, merged) {
+		t.Errorf("unexpected dropped tracks %+v", dropped)
+	}
+	if len(first.Extra) != 1 {
+		t.Errorf("extra attributes of original track should not change, got %v", first.Extra)
+	}
+	// different recordings of the title are not mixed
+	second.Year = "1951-03-02"
+	d = &Discography{Tracks: []Track{first, second}}
+	d.RemoveDuplicates("title")
+	if len(d.Tracks) != 1 || !reflect.DeepEqual(d.Tracks[0], first) {
+		t.Errorf("expected the most complete record as is, got %+v", d.Tracks)
+	}
+
+	if _, err := d.RemoveDuplicates("fuzzy"); err == nil {
+		t.Error("expected error for unsupported policy")
+	}
+}
+
+func TestWriteDiscography(t *testing.T) {
+	expectedDiscography := &Discography{
+		Tracks: []Track{
+			{Name: "Una noche más", Orchestra: "Ricardo Tanturi", Year: "1941", Genre: "Tango", Vocal: "Alberto Castillo"},
+			{Name: "En el salón", Orchestra: "Ricardo Tanturi", Year: "1943", Artist: "Artist"},
+		},
+	}
+	for _, format := range []string{"xml", "csv"} {
+		fname := filepath.Join(t.TempDir(), "test."+format)
+		file, err := os.Create(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := expectedDiscography.Write(file, format); err != nil {
+			t.Fatalf("Failed to write %s: %v", format, err)
+		}
+		file.Close()
+
+		discography, err := ReadFile(fname)
+		if err != nil {
+			t.Fatalf("Failed to read %s file: %v", format, err)
+		}
+		if !reflect.DeepEqual(discography, expectedDiscography) {
+			t.Errorf("fail to process %s file: expected %+v, got %+v", format, expectedDiscography, discography)
+		}
+	}
+	if err := expectedDiscography.Write(os.Stdout, "txt"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+func TestExtraAttributes(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "test.xml")
+	data := `<discography orchestra="Carlos Di Sarli">
+    <track name="Bahía Blanca" year="1957" genre="tango" rating="5" bpm="120" tandaGroup="A"/>
+    <track name="Nada" year="1944" genre="tango" rating="3" bpm="95"/>
+    <track name="Corazón" year="1939" genre="tango" rating="4" bpm="118" tandaGroup="A"/>
+</discography>`
+	if err := os.WriteFile(fname, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	track := d.Tracks[0]
+	if !reflect.DeepEqual(track.Extra, map[string]string{"rating": "5", "bpm": "120", "tandaGroup": "A"}) {
+		t.Errorf("unexpected extra attributes %+v", track.Extra)
+	}
+	if value, ok := track.Attr("tandaGroup"); !ok || value != "A" {
+		t.Errorf("extra attribute is not addressable by name: %q", value)
+	}
+
+	// numeric values are sorted by their values
+	d.SortBy("bpm", "ascending")
+	if d.Tracks[0].Name != "Nada" || d.Tracks[2].Name != "Bahía Blanca" {
+		t.Errorf("wrong order of tracks sorted by bpm %+v", d.Tracks)
+	}
+	d.FilterBy(map[string]string{"tandagroup": "A"})
+	if len(d.Tracks) != 0 {
+		t.Errorf("names of extra attributes are case sensitive, got %+v", d.Tracks)
+	}
+	d, _ = ReadFile(fname)
+	d.FilterBy(map[string]string{"tandaGroup": "a", "genre": "tango"})
+	if len(d.Tracks) != 2 {
+		t.Errorf("wrong tracks filtered by extra attribute %+v", d.Tracks)
+	}
+
+	// extra attributes are round-tripped by all writers
+	for _, format := range []string{"xml", "csv"} {
+		fname := filepath.Join(t.TempDir(), "test."+format)
+		file, err := os.Create(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Write(file, format); err != nil {
+			t.Fatalf("Failed to write %s: %v", format, err)
+		}
+		file.Close()
+		out, err := ReadFile(fname)
+		if err != nil {
+			t.Fatalf("Failed to read %s file: %v", format, err)
+		}
+		if !reflect.DeepEqual(out.Tracks, d.Tracks) {
+			t.Errorf("extra attributes are lost in %s: expected %+v, got %+v", format, d.Tracks, out.Tracks)
+		}
+	}
+	var buf strings.Builder
+	if err := d.Write(&buf, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var out Discography
+	if err := json.Unmarshal([]byte(buf.String()), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out.Tracks, d.Tracks) {
+		t.Errorf("extra attributes are lost in json: expected %+v, got %+v", d.Tracks, out.Tracks)
+	}
+	if !strings.Contains(buf.String(), `"tandaGroup": "A"`) {
+		t.Errorf("json does not contain extra attributes %s", buf.String())
+	}
+
+	// tracks without extra attributes keep short CSV records
+	plain := Track{Orchestra: "Carlos Di Sarli", Year: "1944", Name: "Nada"}
+	if record := plain.Record(); len(record) != 6 {
+		t.Errorf("unexpected record %v", record)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/vkuznet/goplaylist/discography
+
+go 1.23.3
+
+require github.com/vkuznet/goplaylist/normalize v0.0.0
+
+require golang.org/x/text v0.21.0 // indirect
+
+// normalize package is taken from the sibling module
+replace github.com/vkuznet/goplaylist/normalize => ../normalize
@@ -0,0 +1,156 @@
+package discography
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ReadXMLFile reads XML discography file(s) matching given glob pattern
+func ReadXMLFile(filename string) (*Discography, error) {
+	files, err := glob(filename)
+	if err != nil {
+		return nil, err
+	}
+	discography, _, err := load(files, parseXMLFile, LoadOptions{Strict: true})
+	return discography, err
+}
+
+// ReadCSVFile reads CSV discography file(s) matching given glob pattern
+func ReadCSVFile(filename string) (*Discography, error) {
+	files, err := glob(filename)
+	if err != nil {
+		return nil, err
+	}
+	discography, _, err := load(files, parseCSVFile, LoadOptions{Strict: true})
+	return discography, err
+}
+
+// ReadFile reads discography file(s) matching given glob pattern, the format
+// is determined by file extension: xml or csv
+func ReadFile(filename string) (*Discography, error) {
+	discography, _, err := Load(filename, LoadOptions{Strict: true})
+	return discography, err
+}
+
+// helper function to parse discography file, the format is determined by
+// file extension: xml or csv
+func parseFile(filename string) (*Discography, error) {
+	switch ext := filepath.Ext(filename); ext {
+	case ".xml":
+		return parseXMLFile(filename)
+	case ".csv":
+		return parseCSVFile(filename)
+	default:
+		return nil, fmt.Errorf("unsupported file format: %s", ext)
+	}
+}
+
+// helper function to parse XML discography file, the tracks are decoded
+// one by one while the file is streamed
+func parseXMLFile(filename string) (*Discography, error) {
+	file, err := os.Open(filepath.Clean(filename))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var root bool
+	discography := &Discography{}
+	decoder := xml.NewDecoder(bufio.NewReader(file))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return discography, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		elem, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch {
+		case !root:
+			// root element, e.g. discography, provides orchestra of all tracks
+			root = true
+			for _, attr := range elem.Attr {
+				if attr.Name.Local == "orchestra" {
+					discography.Orchestra = attr.Value
+				}
+			}
+		case elem.Name.Local == "track":
+			var track Track
+			if err := decoder.DecodeElement(&track, &elem); err != nil {
+				return nil, err
+			}
+			discography.Tracks = append(discography.Tracks, track)
+		}
+	}
+}
+
+// helper function to parse CSV discography file, the records are read one
+// by one while the file is streamed
+func parseCSVFile(filename string) (*Discography, error) {
+	file, err := os.Open(filepath.Clean(filename))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	discography := &Discography{}
+	reader := csv.NewReader(bufio.NewReader(file))
+	reader.FieldsPerRecord = -1 // Allow variable number of fields per line
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return discography, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 3 {
+			continue // Skip rows with insufficient data
+		}
+		// NOTE: record should match Fields
+		discography.Tracks = append(discography.Tracks, TrackFromRecord(record))
+	}
+}
+
+// Write writes discography in given format: xml, csv or json
+func (d *Discography) Write(w io.Writer, format string) error {
+	switch format {
+	case "xml":
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		encoder := xml.NewEncoder(w)
+		encoder.Indent("", "    ")
+		start := xml.StartElement{Name: xml.Name{Local: "discography"}}
+		if err := encoder.EncodeElement(d, start); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	case "csv":
+		writer := csv.NewWriter(w)
+		for _, track := range d.Tracks {
+			// NOTE: record should match ReadCSVFile
+			if err := writer.Write(track.Record()); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "    ")
+		return encoder.Encode(d)
+	}
+	return fmt.Errorf("unsupported export format: %s", format)
+}
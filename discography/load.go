@@ -0,0 +1,128 @@
+package discography
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// LoadOptions represents options of loading many discography files
+type LoadOptions struct {
+	Workers int  // number of files parsed concurrently, zero means number of CPUs
+	Strict  bool // abort loading on the first file which can not be read
+}
+
+// FileError represents error of reading single discography file
+type FileError struct {
+	File string
+	Err  error
+}
+
+// Error returns description of the error
+func (e *FileError) Error() string {
+	return fmt.Sprintf("failed to read file %s: %v", e.File, e.Err)
+}
+
+// Unwrap returns underlying error
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// helper function to find files matching given glob pattern
+func glob(pattern string) ([]string, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match files with pattern %s: %v", pattern, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files matched the pattern %s", pattern)
+	}
+	return files, nil
+}
+
+// Load reads discography files matching given glob pattern, the files are
+// parsed concurrently by a pool of workers and their tracks are combined in
+// order of file names, i.e. the result does not depend on the order in which
+// the files are parsed. The files which can not be read are returned as list
+// of errors and skipped, unless strict option is set in which case the error
+// of the first such file is returned. It is also an error if none of the
+// files can be read.
+func Load(pattern string, opts LoadOptions) (*Discography, []*FileError, error) {
+	files, err := glob(pattern)
+	if err != nil {
+		return nil, nil, err
+	}
+	return load(files, parseFile, opts)
+}
+
+// helper function to parse given files with a pool of workers and combine
+// their discographies in order of files
+func load(files []string, parse func(string) (*Discography, error), opts LoadOptions) (*Discography, []*FileError, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = min(workers, len(files))
+
+	discographies := make([]*Discography, len(files))
+	errs := make([]error, len(files))
+	var failed atomic.Bool
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				discographies[idx], errs[idx] = parse(files[idx])
+				if errs[idx] != nil {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+	for idx := range files {
+		// in strict mode there is no reason to parse remaining files
+		if opts.Strict && failed.Load() {
+			break
+		}
+		indexes <- idx
+	}
+	close(indexes)
+	wg.Wait()
+
+	combinedDiscography := &Discography{}
+	var fileErrors []*FileError
+	for idx, discography := range discographies {
+		if errs[idx] != nil {
+			ferr := &FileError{File: files[idx], Err: errs[idx]}
+			if opts.Strict {
+				return nil, nil, ferr
+			}
+			fileErrors = append(fileErrors, ferr)
+			continue
+		}
+		if discography == nil {
+			continue
+		}
+
+		// Patch orchestra in tracks if necessary
+		for _, track := range discography.Tracks {
+			if track.Orchestra == "" && discography.Orchestra != "" {
+				track.Orchestra = discography.Orchestra
+			}
+			combinedDiscography.Tracks = append(combinedDiscography.Tracks, track)
+		}
+
+		// Set the orchestra attribute if it's not already set
+		if combinedDiscography.Orchestra == "" && discography.Orchestra != "" {
+			combinedDiscography.Orchestra = discography.Orchestra
+		}
+	}
+	if len(fileErrors) == len(files) {
+		return nil, fileErrors, fileErrors[0]
+	}
+	return combinedDiscography, fileErrors, nil
+}
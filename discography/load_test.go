@@ -0,0 +1,72 @@
+package discography
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 20; i++ {
+		data := fmt.Sprintf(`<discography orchestra="Orchestra %02d">
+    <track name="Track %02d-1" year="1940"/>
+    <track name="Track %02d-2" year="1941"/>
+</discography>`, i, i, i)
+		fname := filepath.Join(dir, fmt.Sprintf("orchestra%02d.xml", i))
+		if err := os.WriteFile(fname, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "orchestra20.csv"), []byte("Orchestra 20,1942,Track 20-1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// tracks follow order of files regardless of number of workers
+	for _, workers := range []int{0, 1, 3, 50} {
+		d, fileErrors, err := Load(filepath.Join(dir, "orchestra*"), LoadOptions{Workers: workers})
+		if err != nil || len(fileErrors) != 0 {
+			t.Fatalf("workers %d: unexpected errors %v %v", workers, err, fileErrors)
+		}
+		if len(d.Tracks) != 41 || d.Orchestra != "Orchestra 00" {
+			t.Fatalf("workers %d: unexpected discography %+v", workers, d)
+		}
+		for idx, track := range d.Tracks[:40] {
+			name := fmt.Sprintf("Track %02d-%d", idx/2, idx%2+1)
+			if track.Name != name || track.Orchestra != fmt.Sprintf("Orchestra %02d", idx/2) {
+				t.Errorf("workers %d: expected %s at %d, got %+v", workers, name, idx, track)
+			}
+		}
+	}
+
+	// malformed files are skipped unless loading is strict
+	bad := filepath.Join(dir, "orchestra05.xml")
+	if err := os.WriteFile(bad, []byte(`<discography><track name="Bad"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, fileErrors, err := Load(filepath.Join(dir, "orchestra*"), LoadOptions{Workers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fileErrors) != 1 || fileErrors[0].File != bad || len(d.Tracks) != 39 {
+		t.Errorf("unexpected result of loading with malformed file %v, %d tracks", fileErrors, len(d.Tracks))
+	}
+	_, _, err = Load(filepath.Join(dir, "orchestra*"), LoadOptions{Workers: 4, Strict: true})
+	var ferr *FileError
+	if !errors.As(err, &ferr) || ferr.File != bad {
+		t.Errorf("expected error of malformed file in strict mode, got %v", err)
+	}
+	if _, err := ReadFile(filepath.Join(dir, "orchestra0*.xml")); err == nil {
+		t.Error("expected error of malformed file")
+	}
+
+	// it is an error if none of files can be read
+	if _, _, err := Load(bad, LoadOptions{}); err == nil {
+		t.Error("expected error when no files can be read")
+	}
+	if _, _, err := Load(filepath.Join(dir, "missing*.xml"), LoadOptions{}); err == nil {
+		t.Error("expected error when no files match the pattern")
+	}
+}
@@ -0,0 +1,265 @@
+package discography
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vkuznet/goplaylist/normalize"
+)
+
+// Track represents track
+type Track struct {
+	Orchestra string        `xml:"orchestra,attr"`
+	Year      RecordingDate `xml:"year,attr"`
+	Name      string        `xml:"name,attr"`
+	Artist    string        `xml:"artist,attr,omitempty"`
+	Genre     string        `xml:"genre,attr,omitempty"`
+	Vocal     string        `xml:"vocal,attr,omitempty"`
+	Composer  string        `xml:"composer,attr,omitempty" json:",omitempty"`
+	Author    string        `xml:"author,attr,omitempty" json:",omitempty"` // lyricist
+	Label     string        `xml:"label,attr,omitempty" json:",omitempty"`
+	Matrix    string        `xml:"matrix,attr,omitempty" json:",omitempty"`   // matrix or catalogue number
+	Duration  string        `xml:"duration,attr,omitempty" json:",omitempty"` // e.g. 2:45
+	ISRC      string        `xml:"isrc,attr,omitempty" json:",omitempty"`
+	SpotifyID string        `xml:"spotify_id,attr,omitempty" json:",omitempty"`
+	YoutubeID string        `xml:"youtube_id,attr,omitempty" json:",omitempty"`
+	// Extra holds attributes which are not part of the model, e.g. rating,
+	// bpm or tandaGroup, they are kept as is by readers and writers
+	Extra map[string]string `xml:"-" json:",omitempty"`
+}
+
+// helper type to decode and encode track attributes without recursion into
+// XML methods of the track
+type plainTrack Track
+
+// helper type to capture track attributes which are not part of the model
+type xmlTrack struct {
+	plainTrack
+	Attrs []xml.Attr `xml:",any,attr"`
+}
+
+// UnmarshalXML decodes track element keeping its unknown attributes in Extra
+func (t *Track) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v xmlTrack
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	*t = Track(v.plainTrack)
+	for _, attr := range v.Attrs {
+		t.SetExtra(attr.Name.Local, attr.Value)
+	}
+	return nil
+}
+
+// MarshalXML encodes track element along with its extra attributes
+func (t *Track) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	v := xmlTrack{plainTrack: plainTrack(*t)}
+	for _, name := range t.ExtraNames() {
+		v.Attrs = append(v.Attrs, xml.Attr{Name: xml.Name{Local: name}, Value: t.Extra[name]})
+	}
+	return e.EncodeElement(v, start)
+}
+
+// SetExtra sets attribute of the track which is not part of the model
+func (t *Track) SetExtra(name, value string) {
+	if t.Extra == nil {
+		t.Extra = make(map[string]string)
+	}
+	t.Extra[name] = value
+}
+
+// ExtraNames provides sorted names of extra attributes of the track
+func (t *Track) ExtraNames() []string {
+	var names []string
+	for name := range t.Extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Fields lists track attributes in order of CSV columns
+var Fields = []string{
+	"orchestra", "year", "name", "artist", "genre", "vocal",
+	"composer", "author", "label", "matrix", "duration", "isrc", "spotify_id", "youtube_id",
+}
+
+// Attr provides track attribute by its name
+func (t *Track) Attr(name string) (string, bool) {
+	switch name {
+	case "orchestra":
+		return t.Orchestra, true
+	case "year":
+		return string(t.Year), true
+	case "name":
+		return t.Name, true
+	case "artist":
+		return t.Artist, true
+	case "genre":
+		return t.Genre, true
+	case "vocal":
+		return t.Vocal, true
+	case "composer":
+		return t.Composer, true
+	case "author":
+		return t.Author, true
+	case "label":
+		return t.Label, true
+	case "matrix":
+		return t.Matrix, true
+	case "duration":
+		return t.Duration, true
+	case "isrc":
+		return t.ISRC, true
+	case "spotify_id":
+		return t.SpotifyID, true
+	case "youtube_id":
+		return t.YoutubeID, true
+	}
+	value, ok := t.Extra[name]
+	return value, ok
+}
+
+// SetAttr sets track attribute by its name
+func (t *Track) SetAttr(name, value string) bool {
+	switch name {
+	case "orchestra":
+		t.Orchestra = value
+	case "year":
+		t.Year = RecordingDate(value)
+	case "name":
+		t.Name = value
+	case "artist":
+		t.Artist = value
+	case "genre":
+		t.Genre = value
+	case "vocal":
+		t.Vocal = value
+	case "composer":
+		t.Composer = value
+	case "author":
+		t.Author = value
+	case "label":
+		t.Label = value
+	case "matrix":
+		t.Matrix = value
+	case "duration":
+		t.Duration = value
+	case "isrc":
+		t.ISRC = value
+	case "spotify_id":
+		t.SpotifyID = value
+	case "youtube_id":
+		t.YoutubeID = value
+	default:
+		return false
+	}
+	return true
+}
+
+// TrackFromRecord constructs track from CSV record, columns follow
+// order of Fields and the following columns hold extra attributes as
+// name=value pairs
+func TrackFromRecord(record []string) Track {
+	var track Track
+	for idx, value := range record {
+		if idx < len(Fields) {
+			track.SetAttr(Fields[idx], value)
+		} else if arr := strings.SplitN(value, "=", 2); len(arr) == 2 && arr[0] != "" {
+			track.SetExtra(arr[0], arr[1])
+		}
+	}
+	return track
+}
+
+// Record provides CSV record of the track, extended columns
+// are written only when they are provided and extra attributes follow
+// all of them as name=value pairs
+func (t *Track) Record() []string {
+	var record []string
+	for _, name := range Fields {
+		value, _ := t.Attr(name)
+		record = append(record, value)
+	}
+	if len(t.Extra) > 0 {
+		for _, name := range t.ExtraNames() {
+			record = append(record, name+"="+t.Extra[name])
+		}
+		return record
+	}
+	last := 6
+	for idx := len(record) - 1; idx >= last; idx-- {
+		if record[idx] != "" {
+			last = idx + 1
+			break
+		}
+	}
+	return record[:last]
+}
+
+// ServiceID provides ID of the track in given service if it is known
+func (t *Track) ServiceID(service string) string {
+	switch service {
+	case "spotify":
+		return t.SpotifyID
+	case "youtube":
+		return t.YoutubeID
+	}
+	return ""
+}
+
+// NormalizeISRC normalizes ISRC code, e.g. US-RC1-76-07839 is USRC17607839
+func NormalizeISRC(isrc string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isrc))
+}
+
+// DurationSeconds converts duration of the track, e.g. 2:45 or 165, to
+// number of seconds, it returns 0 for unknown duration
+func DurationSeconds(duration string) int {
+	var seconds int
+	for _, part := range strings.Split(strings.TrimSpace(duration), ":") {
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + value
+	}
+	return seconds
+}
+
+// String provides string representation of the track
+func (t *Track) String() string {
+	return fmt.Sprintf("%s,%s,%s,%s", t.Orchestra, t.Year, t.Name, t.Artist)
+}
+
+// Key provides comparison key of the track, tracks with the same key are
+// considered equal regardless of case, diacritics, punctuation and orchestra
+// name variants, e.g. in local cache
+func (t *Track) Key() string {
+	return fmt.Sprintf("%s|%s|%s|%s",
+		normalize.OrchestraKey(t.Orchestra), t.Year.Key(), normalize.Key(t.Name), normalize.Key(t.Artist))
+}
+
+// ParseTrack constructs track from its string representation
+func ParseTrack(t string) Track {
+	record := strings.Split(t, ",")
+	// NOTE: record should match String() method
+	track := Track{
+		Orchestra: record[0],
+		Year:      RecordingDate(record[1]),
+		Name:      record[2],
+	}
+	if len(record) > 3 {
+		track.Artist = record[3]
+	}
+	if len(record) > 4 {
+		track.Genre = record[4]
+	}
+	if len(record) > 5 {
+		track.Vocal = record[5]
+	}
+	return track
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vkuznet/goplaylist/normalize"
+)
+
+// defaultDescription defines default template of playlist description
+const defaultDescription = "{orchestras}, {years}: {tracks} tracks ({genres})"
+
+// FactCount represents attribute value along with number of tracks having it
+type FactCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// PlaylistFacts represents facts about discography used in playlist
+// descriptions and titles
+type PlaylistFacts struct {
+	Title      string
+	Orchestras []FactCount
+	Genres     []FactCount
+	Vocalists  []FactCount
+	MinYear    string
+	MaxYear    string
+	Tracks     int
+	Date       string // date when facts were collected, i.e. today
+	Group      string // value of split attribute of the playlist
+}
+
+// helper function to count attribute values, the values are compared after
+// normalization and sorted by number of tracks and then alphabetically
+func countValues(values []string) []FactCount {
+	counts := make(map[string]int)
+	names := make(map[string]string)
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		key := normalize.Key(v)
+		if _, ok := names[key]; !ok {
+			names[key] = v
+		}
+		counts[key]++
+	}
+	var result []FactCount
+	for key, count := range counts {
+		result = append(result, FactCount{Value: names[key], Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Value < result[j].Value
+	})
+	return result
+}
+
+// helper function to collect facts about discography
+func discographyFacts(title string, discography *Discography) PlaylistFacts {
+	facts := PlaylistFacts{
+		Title:  title,
+		Tracks: len(discography.Tracks),
+		Date:   time.Now().Format("2006-01-02"),
+	}
+	var orchestras, genres, vocalists []string
+	for _, track := range discography.Tracks {
+		orchestra := track.Orchestra
+		if orchestra == "" {
+			orchestra = discography.Orchestra
+		}
+		orchestras = append(orchestras, orchestra)
+		genres = append(genres, track.Genre)
+		vocalists = append(vocalists, track.Vocal)
+		from, to := track.Year.YearRange()
+		if from == 0 {
+			continue
+		}
+		if minYear := fmt.Sprintf("%d", from); facts.MinYear == "" || minYear < facts.MinYear {
+			facts.MinYear = minYear
+		}
+		if maxYear := fmt.Sprintf("%d", to); maxYear > facts.MaxYear {
+			facts.MaxYear = maxYear
+		}
+	}
+	facts.Orchestras = countValues(orchestras)
+	facts.Genres = countValues(genres)
+	facts.Vocalists = countValues(vocalists)
+	return facts
+}
+
+// helper function to join values of fact counts
+func joinValues(counts []FactCount, sep string) string {
+	var values []string
+	for _, c := range counts {
+		values = append(values, c.Value)
+	}
+	return strings.Join(values, sep)
+}
+
+// YearRange returns year range of the facts, e.g. 1927-1963
+func (f PlaylistFacts) YearRange() string {
+	if f.MinYear == f.MaxYear {
+		return f.MinYear
+	}
+	return fmt.Sprintf("%s-%s", f.MinYear, f.MaxYear)
+}
+
+// GenreBreakdown returns number of tracks per genre, e.g. "8 tango, 2 vals"
+func (f PlaylistFacts) GenreBreakdown() string {
+	var values []string
+	for _, c := range f.Genres {
+		values = append(values, fmt.Sprintf("%d %s", c.Count, strings.ToLower(c.Value)))
+	}
+	return strings.Join(values, ", ")
+}
+
+// Values returns map of template placeholders and their values, singular
+// placeholders (orchestra, genre, vocal) hold distinct values of the
+// attribute while genres placeholder holds number of tracks per genre,
+// group placeholder holds value of split attribute
+func (f PlaylistFacts) Values() map[string]string {
+	values := map[string]string{
+		"title":      f.Title,
+		"orchestra":  joinValues(f.Orchestras, ", "),
+		"orchestras": joinValues(f.Orchestras, ", "),
+		"genre":      joinValues(f.Genres, ", "),
+		"genres":     f.GenreBreakdown(),
+		"vocal":      joinValues(f.Vocalists, ", "),
+		"vocalists":  joinValues(f.Vocalists, ", "),
+		"minYear":    f.MinYear,
+		"maxYear":    f.MaxYear,
+		"years":      f.YearRange(),
+		"tracks":     fmt.Sprintf("%d", f.Tracks),
+		"date":       f.Date,
+		"group":      f.Group,
+	}
+	return values
+}
+
+// templateRegexp matches {placeholder} in templates
+var templateRegexp = regexp.MustCompile(`\{([a-zA-Z]+)\}`)
+
+// helper function to expand {placeholder} entries of the template with
+// values of given facts, unknown placeholders are kept as is
+func expandTemplate(tmpl string, facts PlaylistFacts) string {
+	values := facts.Values()
+	out := templateRegexp.ReplaceAllStringFunc(tmpl, func(m string) string {
+		if v, ok := values[m[1:len(m)-1]]; ok {
+			return v
+		}
+		return m
+	})
+	return strings.Join(strings.Fields(out), " ")
+}
+
+// helper function to check if given string is a template with placeholders
+func isTemplate(tmpl string) bool {
+	return templateRegexp.MatchString(tmpl)
+}
+
+// helper function to construct playlist description from given template,
+// default template is used if it is empty
+func playlistDescription(title string, discography *Discography, tmpl string) string {
+	if tmpl == "" {
+		tmpl = defaultDescription
+	}
+	return expandTemplate(tmpl, discographyFacts(title, discography))
+}
+
+// helper function to construct note about the track, e.g. recording date and vocalist
+func trackNote(track Track) string {
+	var notes []string
+	if track.Year != "" {
+		notes = append(notes, "recorded "+string(track.Year))
+	}
+	if track.Vocal != "" {
+		notes = append(notes, "vocal "+track.Vocal)
+	}
+	if track.Genre != "" {
+		notes = append(notes, strings.ToLower(track.Genre))
+	}
+	return strings.Join(notes, ", ")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlaylistDescription(t *testing.T) {
+	discography, err := readFile("testplaylist.xml", "", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	facts := discographyFacts("test", discography)
+	if facts.YearRange() != "1927-1963" || facts.Tracks != 10 {
+		t.Errorf("wrong facts %+v", facts)
+	}
+	if genres := facts.GenreBreakdown(); genres != "8 tango, 2 vals" {
+		t.Errorf("wrong genre breakdown '%s'", genres)
+	}
+
+	discography.Tracks = discography.Tracks[3:6]
+	desc := playlistDescription("Troilo", discography, "{title}: {orchestras} {unknown}")
+	if desc != "Troilo: Anibal Troilo {unknown}" {
+		t.Errorf("wrong description '%s'", desc)
+	}
+
+	note := trackNote(Track{Year: "1938-04-18", Vocal: "Ángel Vargas", Genre: "Vals"})
+	if note != "recorded 1938-04-18, vocal Ángel Vargas, vals" {
+		t.Errorf("wrong track note '%s'", note)
+	}
+}
+
+func TestPlaylistTitle(t *testing.T) {
+	discography, err := readFile("testplaylist.xml", "", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	discography.Tracks = discography.Tracks[3:6]
+	opts := Options{
+		File:  "/data/testplaylist.xml",
+		Title: "{orchestra} – {genre} ({minYear}–{maxYear}), {vocal}, {tracks} tracks [{title}]",
+	}
+	title, err := opts.playlistTitle(discography)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Anibal Troilo – Tango (1951–1963), Instrumental, 3 tracks [testplaylist]" {
+		t.Errorf("wrong playlist title '%s'", title)
+	}
+
+	opts.Title = "Troilo {date}"
+	title, err = opts.playlistTitle(discography)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := time.Parse("Troilo 2006-01-02", title); err != nil {
+		t.Errorf("wrong playlist title with date '%s'", title)
+	}
+
+	// title without placeholders is taken as is and file name is the default one
+	opts.Title = "My  playlist"
+	if title, _ := opts.playlistTitle(discography); title != "My  playlist" {
+		t.Errorf("wrong plain playlist title '%s'", title)
+	}
+	opts.Title = ""
+	if title, _ := opts.playlistTitle(discography); title != "testplaylist" {
+		t.Errorf("wrong default playlist title '%s'", title)
+	}
+}
@@ -2,6 +2,18 @@ module github.com/vkuznet/goplaylist
 
 go 1.23.3
 
+require (
+	github.com/dhowden/tag v0.0.0-20240417053706-3d75831295e8
+	github.com/vkuznet/goplaylist/discography v0.0.0
+	github.com/vkuznet/goplaylist/normalize v0.0.0
+	github.com/zmb3/spotify/v2 v2.4.3
+	golang.org/x/image v0.23.0
+	golang.org/x/net v0.31.0
+	golang.org/x/oauth2 v0.24.0
+	google.golang.org/api v0.206.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
 require (
 	cloud.google.com/go/auth v0.10.2 // indirect
 	cloud.google.com/go/auth/oauth2adapt v0.2.5 // indirect
@@ -14,19 +26,22 @@ require (
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/googleapis/enterprise-certificate-proxy v0.3.4 // indirect
 	github.com/googleapis/gax-go/v2 v2.14.0 // indirect
-	github.com/zmb3/spotify/v2 v2.4.3 // indirect
 	go.opencensus.io v0.24.0 // indirect
 	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.54.0 // indirect
 	go.opentelemetry.io/otel v1.29.0 // indirect
 	go.opentelemetry.io/otel/metric v1.29.0 // indirect
 	go.opentelemetry.io/otel/trace v1.29.0 // indirect
 	golang.org/x/crypto v0.29.0 // indirect
-	golang.org/x/net v0.31.0 // indirect
-	golang.org/x/oauth2 v0.24.0 // indirect
 	golang.org/x/sys v0.27.0 // indirect
-	golang.org/x/text v0.20.0 // indirect
-	google.golang.org/api v0.206.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20241104194629-dd2ea8efbc28 // indirect
 	google.golang.org/grpc v1.67.1 // indirect
 	google.golang.org/protobuf v1.35.1 // indirect
 )
+
+// shared packages are separate modules, so sister tools do not depend on
+// libraries of goplaylist services
+replace (
+	github.com/vkuznet/goplaylist/discography => ./discography
+	github.com/vkuznet/goplaylist/normalize => ./normalize
+)
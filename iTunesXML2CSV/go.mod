@@ -1,3 +1,16 @@
 module iTunesXML2CSV
 
 go 1.23.3
+
+require github.com/vkuznet/goplaylist/discography v0.0.0
+
+require (
+	github.com/vkuznet/goplaylist/normalize v0.0.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+)
+
+// shared packages are taken from sibling modules of goplaylist
+replace (
+	github.com/vkuznet/goplaylist/discography => ../discography
+	github.com/vkuznet/goplaylist/normalize => ../normalize
+)
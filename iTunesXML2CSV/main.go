@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"github.com/vkuznet/goplaylist/discography"
 )
 
 type Dict struct {
@@ -85,12 +87,14 @@ func main() {
 			}
 		}
 		if trackID > 0 && trackName != "" && artist != "" && genre != "" && year > 0 {
-			record := []string{
-				artist,
-				fmt.Sprintf("%d", year),
-				trackName,
-				genre,
+			// write records in goplaylist CSV format, iTunes artist is the orchestra
+			track := discography.Track{
+				Orchestra: artist,
+				Year:      discography.RecordingDate(fmt.Sprintf("%d", year)),
+				Name:      trackName,
+				Genre:     genre,
 			}
+			record := track.Record()
 			line := strings.Join(record, ",")
 			if _, ok := playlist[line]; !ok {
 				writer.Write(record)
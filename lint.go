@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vkuznet/goplaylist/discography"
+	"github.com/vkuznet/goplaylist/normalize"
+)
+
+// knownGenres lists genres expected in discography files, genres which
+// have cover theme in configuration are known as well
+var knownGenres = []string{
+	"tango", "vals", "milonga", "candombe", "foxtrot", "ranchera",
+	"pasodoble", "polca", "corrido", "cancion", "zamba", "chacarera",
+}
+
+// LintIssue represents problem found in discography file
+type LintIssue struct {
+	File    string // name of the file
+	Line    int    // line of the file
+	Message string // description of the problem
+	Fixed   bool   // problem is fixed in place
+}
+
+// String provides string representation of the issue
+func (i LintIssue) String() string {
+	msg := fmt.Sprintf("%s:%d: %s", i.File, i.Line, i.Message)
+	if i.Fixed {
+		msg += " (fixed)"
+	}
+	return msg
+}
+
+// lintRecord represents track of discography file along with its location
+// in the file and safe fixes of its attributes
+type lintRecord struct {
+	Track  Track
+	Line   int
+	start  int64             // offset of XML element
+	end    int64             // end offset of XML element
+	fields []string          // fields of CSV record
+	fixes  map[string]string // attribute name to its fixed value
+}
+
+// helper function to set fixed value of track attribute, subsequent checks
+// see the fixed value
+func (r *lintRecord) fix(attr, value string) {
+	if r.fixes == nil {
+		r.fixes = make(map[string]string)
+	}
+	r.fixes[attr] = value
+	r.Track.SetAttr(attr, value)
+}
+
+// lintFile represents discography file being checked
+type lintFile struct {
+	Name      string
+	Format    string      // xml or csv
+	Header    *lintRecord // discography element of XML file
+	Records   []lintRecord
+	Issues    []LintIssue
+	data      []byte
+	csvFields [][]string // all CSV records including skipped ones
+}
+
+// helper function to report issue of the file
+func (f *lintFile) report(line int, fixed bool, format string, args ...any) {
+	f.Issues = append(f.Issues, LintIssue{File: f.Name, Line: line, Message: fmt.Sprintf(format, args...), Fixed: fixed})
+}
+
+// helper function to get line number of given offset of XML file
+func (f *lintFile) line(offset int64) int {
+	return 1 + bytes.Count(f.data[:offset], []byte("\n"))
+}
+
+// helper function to parse XML discography file keeping location of tracks
+func (f *lintFile) parseXML() error {
+	dec := xml.NewDecoder(bytes.NewReader(f.data))
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch se.Name.Local {
+		case "discography":
+			header := &lintRecord{Line: f.line(start), start: start, end: dec.InputOffset()}
+			for _, attr := range se.Attr {
+				if attr.Name.Local == "orchestra" {
+					header.Track.Orchestra = attr.Value
+				}
+			}
+			f.Header = header
+		case "track":
+			var track Track
+			if err := dec.DecodeElement(&track, &se); err != nil {
+				return err
+			}
+			f.Records = append(f.Records, lintRecord{Track: track, Line: f.line(start), start: start, end: dec.InputOffset()})
+		}
+	}
+}
+
+// helper function to parse CSV discography file keeping location of tracks,
+// the records are read in the same way as discography.ReadCSVFile does
+func (f *lintFile) parseCSV() error {
+	reader := csv.NewReader(bytes.NewReader(f.data))
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		f.csvFields = append(f.csvFields, record)
+		line, _ := reader.FieldPos(0)
+		if len(record) < 3 {
+			f.report(line, false, "record has %d fields, at least orchestra, year and name are required", len(record))
+			continue
+		}
+		f.Records = append(f.Records, lintRecord{Track: discography.TrackFromRecord(record), Line: line, fields: record})
+	}
+}
+
+// helper function to check the date of the track, it returns fixed date
+// if the problem can be safely fixed
+func checkDate(date RecordingDate) (RecordingDate, error) {
+	if date == "" {
+		return "", errors.New("missing date")
+	}
+	if err := date.Validate(); err != nil {
+		// dates like 1951/03/02 or 1951.03.02 can be fixed
+		fixed := RecordingDate(strings.NewReplacer("/", "-", ".", "-").Replace(string(date)))
+		if fixed != date && fixed.Validate() == nil {
+			if _, err := checkDate(fixed); err == nil {
+				return fixed, fmt.Errorf("malformed date %q", date)
+			}
+		}
+		return "", err
+	}
+	if from, _ := date.YearRange(); from < 1880 || from > time.Now().Year() {
+		return "", fmt.Errorf("impossible date %q, year is out of range", date)
+	}
+	return "", nil
+}
+
+// helper function to check if genre is known
+func knownGenre(genre string) bool {
+	key := normalize.Key(genre)
+	for _, g := range knownGenres {
+		if g == key {
+			return true
+		}
+	}
+	for g := range Config.CoverThemes {
+		if g != "default" && normalize.Key(g) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// helper function to check tracks of the file and collect found issues
+func (f *lintFile) check() {
+	if f.Header != nil {
+		if v := f.Header.Track.Orchestra; v != strings.Join(strings.Fields(v), " ") {
+			f.Header.fix("orchestra", strings.Join(strings.Fields(v), " "))
+			f.report(f.Header.Line, true, "suspicious whitespace in discography orchestra %q", v)
+		}
+	}
+	var orchestra string
+	if f.Header != nil {
+		orchestra = f.Header.Track.Orchestra
+	}
+
+	for idx := range f.Records {
+		r := &f.Records[idx]
+		for _, attr := range discography.Fields {
+			v, _ := r.Track.Attr(attr)
+			if clean := strings.Join(strings.Fields(v), " "); clean != v {
+				r.fix(attr, clean)
+				f.report(r.Line, true, "suspicious whitespace in %s %q", attr, v)
+			}
+		}
+		if r.Track.Name == "" {
+			f.report(r.Line, false, "missing track name")
+		}
+		if r.Track.Orchestra == "" && orchestra == "" {
+			f.report(r.Line, false, "missing orchestra")
+		}
+		if r.Track.Orchestra != "" && orchestra != "" &&
+			normalize.OrchestraKey(r.Track.Orchestra) != normalize.OrchestraKey(orchestra) {
+			f.report(r.Line, false, "orchestra %q differs from discography orchestra %q", r.Track.Orchestra, orchestra)
+		}
+		if fixed, err := checkDate(r.Track.Year); err != nil {
+			if fixed != "" {
+				r.fix("year", string(fixed))
+			}
+			f.report(r.Line, fixed != "", "%v", err)
+		}
+		if r.Track.Genre != "" && !knownGenre(r.Track.Genre) {
+			f.report(r.Line, false, "unknown genre %q", r.Track.Genre)
+		}
+	}
+	f.checkTitles()
+	f.checkDuplicates()
+	sort.SliceStable(f.Issues, func(i, j int) bool { return f.Issues[i].Line < f.Issues[j].Line })
+}
+
+// helper function to check that the same title is spelled consistently
+// across the file, titles which differ by capitalization only are fixed
+// to the most used spelling
+func (f *lintFile) checkTitles() {
+	var keys []string
+	spellings := make(map[string]map[string]int)
+	for _, r := range f.Records {
+		key := normalize.Key(r.Track.Name)
+		if key == "" {
+			continue
+		}
+		if _, ok := spellings[key]; !ok {
+			keys = append(keys, key)
+			spellings[key] = make(map[string]int)
+		}
+		spellings[key][r.Track.Name]++
+	}
+	best := make(map[string]string)
+	for _, key := range keys {
+		for spelling, count := range spellings[key] {
+			b := best[key]
+			if b == "" || count > spellings[key][b] || (count == spellings[key][b] && spelling < b) {
+				best[key] = spelling
+			}
+		}
+	}
+	for idx := range f.Records {
+		r := &f.Records[idx]
+		b := best[normalize.Key(r.Track.Name)]
+		if b == "" || b == r.Track.Name {
+			continue
+		}
+		name := r.Track.Name
+		if strings.EqualFold(name, b) {
+			r.fix("name", b)
+			f.report(r.Line, true, "inconsistent capitalization of title %q, elsewhere %q", name, b)
+		} else {
+			f.report(r.Line, false, "inconsistent spelling of title %q, elsewhere %q", name, b)
+		}
+	}
+}
+
+// helper function to check duplicate entries of the file
+func (f *lintFile) checkDuplicates() {
+	seen := make(map[string]int)
+	for _, r := range f.Records {
+		key, _ := r.Track.DedupeKey("exact")
+		if line, ok := seen[key]; ok {
+			f.report(r.Line, false, "duplicate of track at line %d", line)
+			continue
+		}
+		seen[key] = r.Line
+	}
+}
+
+// attrPattern matches attribute of XML element
+var attrPattern = regexp.MustCompile(`(\w+)(\s*=\s*)("[^"]*"|'[^']*')`)
+
+// helper function to apply fixes of XML element to its text
+func fixXMLElement(text []byte, fixes map[string]string) []byte {
+	return attrPattern.ReplaceAllFunc(text, func(m []byte) []byte {
+		sub := attrPattern.FindSubmatch(m)
+		value, ok := fixes[string(sub[1])]
+		if !ok {
+			return m
+		}
+		var buf bytes.Buffer
+		buf.Write(sub[1])
+		buf.Write(sub[2])
+		buf.WriteByte('"')
+		xml.EscapeText(&buf, []byte(value))
+		buf.WriteByte('"')
+		return buf.Bytes()
+	})
+}
+
+// helper function to write fixes of the file in place, XML files are patched
+// to preserve their formatting while CSV files are written anew
+func (f *lintFile) write() error {
+	var data []byte
+	switch f.Format {
+	case "csv":
+		// fields of the records share memory with all CSV records
+		for _, r := range f.Records {
+			for i, attr := range discography.Fields {
+				if value, ok := r.fixes[attr]; ok && i < len(r.fields) {
+					r.fields[i] = value
+				}
+			}
+		}
+		var buf bytes.Buffer
+		writer := csv.NewWriter(&buf)
+		if err := writer.WriteAll(f.csvFields); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	default:
+		records := f.Records
+		if f.Header != nil {
+			records = append([]lintRecord{*f.Header}, records...)
+		}
+		var buf bytes.Buffer
+		var offset int64
+		for _, r := range records {
+			if len(r.fixes) == 0 {
+				continue
+			}
+			buf.Write(f.data[offset:r.start])
+			buf.Write(fixXMLElement(f.data[r.start:r.end], r.fixes))
+			offset = r.end
+		}
+		buf.Write(f.data[offset:])
+		data = buf.Bytes()
+	}
+	info, err := os.Stat(f.Name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(f.Name, data, info.Mode())
+}
+
+// helper function to lint discography file, if fix is true safe issues
+// are fixed in place
+func lintDiscography(fname string, fix bool) ([]LintIssue, error) {
+	data, err := os.ReadFile(filepath.Clean(fname))
+	if err != nil {
+		return nil, err
+	}
+	f := &lintFile{Name: fname, Format: "xml", data: data}
+	if filepath.Ext(fname) == ".csv" {
+		f.Format = "csv"
+	}
+	if f.Format == "csv" {
+		err = f.parseCSV()
+	} else {
+		err = f.parseXML()
+	}
+	if err != nil {
+		// malformed file can't be checked any further
+		line := 0
+		var syntaxErr *xml.SyntaxError
+		var parseErr *csv.ParseError
+		if errors.As(err, &syntaxErr) {
+			line = syntaxErr.Line
+		} else if errors.As(err, &parseErr) {
+			line = parseErr.Line
+		}
+		f.report(line, false, "malformed %s: %v", strings.ToUpper(f.Format), err)
+		return f.Issues, nil
+	}
+	f.check()
+	if !fix {
+		for i := range f.Issues {
+			f.Issues[i].Fixed = false
+		}
+		return f.Issues, nil
+	}
+	for _, issue := range f.Issues {
+		if issue.Fixed {
+			return f.Issues, f.write()
+		}
+	}
+	return f.Issues, nil
+}
+
+// lintCommand checks discography files for problems
+func lintCommand(args []string) error {
+	var file string
+	var fix bool
+	fs := newFlagSet("lint", "Check discography files for problems: malformed dates, missing names, unknown genres,\ninconsistent titles, orchestra mismatches, suspicious whitespace and duplicates.")
+	fs.StringVar(&file, "file", "", "xml or csv file to check, may be a glob pattern")
+	fs.BoolVar(&fix, "fix", false, "fix safe issues in place: whitespace, date separators and title capitalization")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if file == "" {
+		return errors.New("no input file is provided, please use -file option")
+	}
+	files, err := filepath.Glob(file)
+	if err != nil {
+		return fmt.Errorf("failed to match files with pattern %s: %v", file, err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no files matched the pattern %s", file)
+	}
+	var problems int
+	for _, fname := range files {
+		issues, err := lintDiscography(fname, fix)
+		if err != nil {
+			return err
+		}
+		for _, issue := range issues {
+			fmt.Println(issue.String())
+			if !issue.Fixed {
+				problems++
+			}
+		}
+	}
+	if problems > 0 {
+		return fmt.Errorf("%d problems found", problems)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintXML(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "test.xml")
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<discography orchestra="Carlos Di Sarli ">
+    <!-- hand maintained -->
+    <track name="Bahía Blanca" year="1957-11-13" genre="tango" orchestra="Carlos Di Sarli"/>
+    <track name="bahía blanca" year="1957/11/14" genre="Tango" orchestra="Carlos Di Sarli"/>
+    <track name="Bahia Blanca" year="1951-02-30" genre="tango" orchestra="Juan D'Arienzo"/>
+    <track name="" year="19x1" genre="waltz" vocal="Jorge  Durán"/>
+    <track name="Bahía Blanca" year="1957-11-13" genre="tango" orchestra="Carlos Di Sarli"/>
+</discography>
+`
+	if err := os.WriteFile(fname, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	issues, err := lintDiscography(fname, false)
+	if err != nil {
+		t.Fatalf("lint failed: %v", err)
+	}
+	expect := []string{
+		`:2: suspicious whitespace in discography orchestra "Carlos Di Sarli "`,
+		`:5: malformed date "1957/11/14"`,
+		`:5: inconsistent capitalization of title "bahía blanca", elsewhere "Bahía Blanca"`,
+		`:6: orchestra "Juan D'Arienzo" differs from discography orchestra "Carlos Di Sarli"`,
+		`:6: impossible date "1951-02-30"`,
+		`:6: inconsistent spelling of title "Bahia Blanca", elsewhere "Bahía Blanca"`,
+		`:7: suspicious whitespace in vocal "Jorge  Durán"`,
+		`:7: missing track name`,
+		`:7: malformed date "19x1", expected YYYY, YYYY-MM or YYYY-MM-DD`,
+		`:7: unknown genre "waltz"`,
+		`:8: duplicate of track at line 4`,
+	}
+	if len(issues) != len(expect) {
+		t.Fatalf("expected %d issues, got %d: %v", len(expect), len(issues), issues)
+	}
+	for i, issue := range issues {
+		if issue.String() != fname+expect[i] {
+			t.Errorf("expected issue %q, got %q", fname+expect[i], issue.String())
+		}
+	}
+
+	// fix safe issues in place and keep formatting of the file
+	if _, err := lintDiscography(fname, true); err != nil {
+		t.Fatalf("lint -fix failed: %v", err)
+	}
+	fixed, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`<discography orchestra="Carlos Di Sarli">`,
+		`<!-- hand maintained -->`,
+		`<track name="Bahía Blanca" year="1957-11-14" genre="Tango" orchestra="Carlos Di Sarli"/>`,
+		`vocal="Jorge Durán"`,
+	} {
+		if !strings.Contains(string(fixed), s) {
+			t.Errorf("fixed file does not contain %s:\n%s", s, fixed)
+		}
+	}
+	issues, _ = lintDiscography(fname, false)
+	if len(issues) != 7 {
+		t.Errorf("expected 7 remaining issues, got %v", issues)
+	}
+}
+
+func TestLintCSV(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "test.csv")
+	data := "Ricardo Tanturi,1941, Una noche más\nRicardo Tanturi,1941\n,1943,En el salón,,milonga\n"
+	if err := os.WriteFile(fname, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	issues, err := lintDiscography(fname, true)
+	if err != nil {
+		t.Fatalf("lint failed: %v", err)
+	}
+	expect := []string{
+		`:1: suspicious whitespace in name " Una noche más" (fixed)`,
+		`:2: record has 2 fields, at least orchestra, year and name are required`,
+		`:3: missing orchestra`,
+	}
+	if len(issues) != len(expect) {
+		t.Fatalf("expected %d issues, got %d: %v", len(expect), len(issues), issues)
+	}
+	for i, issue := range issues {
+		if issue.String() != fname+expect[i] {
+			t.Errorf("expected issue %q, got %q", fname+expect[i], issue.String())
+		}
+	}
+	fixed, _ := os.ReadFile(fname)
+	if !strings.HasPrefix(string(fixed), "Ricardo Tanturi,1941,Una noche más\nRicardo Tanturi,1941\n") {
+		t.Errorf("unexpected fixed file:\n%s", fixed)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dhowden/tag"
+	"github.com/vkuznet/goplaylist/normalize"
+)
+
+// audioExtensions defines list of audio file extensions we scan in local library
+var audioExtensions = []string{".mp3", ".m4a", ".mp4", ".flac", ".ogg", ".aif", ".aiff"}
+
+// LibraryFile represents audio file of local music library
+type LibraryFile struct {
+	Path        string
+	Title       string
+	Artist      string
+	AlbumArtist string
+	Year        string
+	Genre       string
+}
+
+// LibraryMatch represents discography track matched to local library file
+type LibraryMatch struct {
+	Track Track
+	File  LibraryFile
+}
+
+// helper function to resolve discography tracks to files of local library
+// and write M3U8 playlist along with the list of missing recordings. Tracks
+// marked as never added in overrides file are skipped, and query of the
+// override is matched against file titles instead of the track name.
+func writeLocalPlaylist(title string, discography *Discography, files []LibraryFile) (BuildResult, error) {
+	result := BuildResult{Title: title}
+	ptracks := playlistTracks(title, discography)
+	titles := libraryTitles(files)
+	var matches []LibraryMatch
+	var missing []Track
+	for idx, trk := range ptracks {
+		lookup, query := trk, trk.String()
+		override := trackOverrides.Lookup(trk)
+		if override != nil && override.Query != "" {
+			lookup.Name, query = override.Query, override.Query
+		}
+		tr := newTrackResult(idx, trk, query)
+		if override != nil && override.Never {
+			tr.Outcome = OutcomeNever
+			result.record(tr)
+			continue
+		}
+		if file := matchFile(lookup, titles); file != nil {
+			matches = append(matches, LibraryMatch{Track: trk, File: *file})
+			tr.Outcome, tr.ServiceID, tr.MatchedTitle = OutcomeAdded, file.Path, filepath.Base(file.Path)
+		} else {
+			missing = append(missing, trk)
+			tr.Outcome = OutcomeNotFound
+		}
+		result.record(tr)
+	}
+
+	odir := Config.OutputDir
+	if odir == "" {
+		odir = "."
+	}
+	fname := safeFileName(title)
+	m3uFile := filepath.Join(odir, fname+".m3u8")
+	if err := writeM3U(m3uFile, matches); err != nil {
+		return result, fmt.Errorf("unable to write playlist %s: %w", m3uFile, err)
+	}
+	result.URL = m3uFile
+	fmt.Printf("playlist %s is written with %d tracks\n", m3uFile, len(matches))
+	if len(missing) > 0 {
+		missingFile := filepath.Join(odir, fname+"-missing.csv")
+		if err := writeMissing(missingFile, missing); err != nil {
+			return result, fmt.Errorf("unable to write missing tracks %s: %w", missingFile, err)
+		}
+		fmt.Printf("%d recordings are not found in local library, see %s\n", len(missing), missingFile)
+	}
+	return result, nil
+}
+
+// helper function to check if given file has audio extension we support
+func isAudioFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range audioExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// helper function to scan music directory and read tags of audio files,
+// if file has no tags we use its name as a title. Files with malformed tags
+// and files or directories which can not be read are logged and skipped,
+// only failure to read the music directory itself aborts the scan.
+func scanLibrary(dir string) ([]LibraryFile, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []LibraryFile
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			log.Printf("skip %s: %v", path, err)
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || !isAudioFile(info.Name()) {
+			return nil
+		}
+		lfile := LibraryFile{
+			Path:  path,
+			Title: strings.TrimSuffix(info.Name(), filepath.Ext(info.Name())),
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			log.Printf("skip %s: %v", path, err)
+			return nil
+		}
+		defer file.Close()
+		metadata, err := tag.ReadFrom(file)
+		if err == nil {
+			if metadata.Title() != "" {
+				lfile.Title = metadata.Title()
+			}
+			lfile.Artist = metadata.Artist()
+			lfile.AlbumArtist = metadata.AlbumArtist()
+			lfile.Genre = metadata.Genre()
+			if metadata.Year() > 0 {
+				lfile.Year = fmt.Sprintf("%d", metadata.Year())
+			}
+		} else if errors.Is(err, tag.ErrNoTagsFound) {
+			if Config.Verbose > 1 {
+				log.Printf("no tags found in %s", path)
+			}
+		} else {
+			log.Printf("skip %s: unable to read tags: %v", path, err)
+			return nil
+		}
+		files = append(files, lfile)
+		return nil
+	})
+	return files, err
+}
+
+// helper function to check if library file artist matches track orchestra,
+// orchestras are compared after their aliases are resolved, e.g. "OTV"
+// matches "Orquesta Típica Víctor", and either name may contain the other
+// one, e.g. "Troilo" matches "Aníbal Troilo"
+func matchOrchestra(file LibraryFile, orchestra string) bool {
+	orchestra = normalize.OrchestraKey(orchestra)
+	for _, artist := range []string{file.Artist, file.AlbumArtist} {
+		artist = normalize.OrchestraKey(artist)
+		if artist == "" || orchestra == "" {
+			continue
+		}
+		if containsWords(artist, orchestra) || containsWords(orchestra, artist) {
+			return true
+		}
+	}
+	return false
+}
+
+// helper function to index library files by normalized titles
+func libraryTitles(files []LibraryFile) map[string][]LibraryFile {
+	titles := make(map[string][]LibraryFile)
+	for _, file := range files {
+		key := normalize.Key(file.Title)
+		titles[key] = append(titles[key], file)
+	}
+	return titles
+}
+
+// helper function to find the best library file of the track among files
+// indexed by their titles, it returns nil if no file matches the track
+func matchFile(track Track, titles map[string][]LibraryFile) *LibraryFile {
+	var best *LibraryFile
+	bestScore := 0
+	candidates := titles[normalize.Key(track.Name)]
+	for i, file := range candidates {
+		score := 1
+		if file.Artist != "" || file.AlbumArtist != "" {
+			if !matchOrchestra(file, track.Orchestra) {
+				continue
+			}
+			score++
+		}
+		if year := track.Year.YearString(); file.Year != "" && year != "" {
+			if file.Year != year {
+				continue
+			}
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = &candidates[i], score
+		}
+	}
+	return best
+}
+
+// helper function to write extended M3U playlist, the EXTINF lines follow
+// "title - artist" convention used by DJServer copytracks tool
+func writeM3U(fname string, matches []LibraryMatch) error {
+	file, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var lines []string
+	lines = append(lines, "#EXTM3U")
+	for _, match := range matches {
+		title := match.File.Title
+		artist := match.File.Artist
+		if artist == "" {
+			artist = match.Track.Orchestra
+		}
+		lines = append(lines, fmt.Sprintf("#EXTINF:-1,%s - %s", title, artist))
+		lines = append(lines, match.File.Path)
+	}
+	// use CRLF line endings since copytracks splits lines on carriage return
+	_, err = file.WriteString(strings.Join(lines, "\r\n") + "\r\n")
+	return err
+}
+
+// helper function to write list of missing tracks in CSV format which can be
+// used as input discography file
+func writeMissing(fname string, tracks []Track) error {
+	file, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	for _, track := range tracks {
+		writer.Write([]string{track.Orchestra, string(track.Year), track.Name})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// helper function to make file name out of playlist title
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`\/:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// helper function to write minimal mp3 file with ID3v2.3 tag
+func writeTaggedFile(t *testing.T, fname string, frames map[string]string) {
+	var body bytes.Buffer
+	for id, value := range frames {
+		// encode frame text in ISO-8859-1
+		var text []byte
+		for _, r := range value {
+			text = append(text, byte(r))
+		}
+		body.WriteString(id)
+		binary.Write(&body, binary.BigEndian, uint32(len(text)+1))
+		body.Write([]byte{0, 0, 0}) // frame flags and ISO-8859-1 encoding
+		body.Write(text)
+	}
+	size := body.Len()
+	var buf bytes.Buffer
+	buf.WriteString("ID3")
+	buf.Write([]byte{3, 0, 0})
+	// tag size is stored as syncsafe integer
+	buf.Write([]byte{byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)})
+	buf.Write(body.Bytes())
+	buf.Write(make([]byte, 128))
+	if err := os.WriteFile(fname, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalLibrary(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "Troilo"), 0755)
+	writeTaggedFile(t, filepath.Join(dir, "Troilo", "01.mp3"),
+		map[string]string{"TIT2": "La Cumparsita", "TPE1": "Aníbal Troilo", "TYER": "1951"})
+	writeTaggedFile(t, filepath.Join(dir, "Troilo", "02.mp3"),
+		map[string]string{"TIT2": "La cumparsita", "TPE1": "Anibal Troilo", "TYER": "1963"})
+	writeTaggedFile(t, filepath.Join(dir, "Canaro.mp3"),
+		map[string]string{"TIT2": "La cumparsita", "TPE1": "Francisco Canaro", "TYER": "1927"})
+	// artist alias is resolved to the orchestra name
+	writeTaggedFile(t, filepath.Join(dir, "Milonga.mp3"),
+		map[string]string{"TIT2": "Milonga sentimental", "TPE1": "OTV", "TYER": "1933"})
+	// file without tags is matched by its name
+	os.WriteFile(filepath.Join(dir, "Sin rumbo fijo.mp3"), make([]byte, 128), 0644)
+	os.WriteFile(filepath.Join(dir, "cover.jpg"), make([]byte, 128), 0644)
+	// file with truncated tag is skipped without aborting the scan,
+	os.WriteFile(filepath.Join(dir, "Troilo", "00.mp3"), []byte("ID3\x03\x00\x00\x00\x00\x10\x00TIT2"), 0644)
+	// as well as file which can not be opened
+	os.Symlink(filepath.Join(dir, "missing.mp3"), filepath.Join(dir, "broken.mp3"))
+
+	files, err := scanLibrary(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 5 {
+		t.Fatalf("expected 5 audio files, got %d: %+v", len(files), files)
+	}
+	for _, file := range files {
+		if filepath.Base(file.Path) == "00.mp3" {
+			t.Errorf("file with malformed tags should be skipped, got %+v", file)
+		}
+	}
+	if _, err := scanLibrary(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing music directory")
+	}
+
+	odir := t.TempDir()
+	Config.OutputDir = odir
+	trackOverrides = &Overrides{}
+	defer func() { Config = Configuration{}; trackOverrides = nil }()
+	trackOverrides.Add(Override{Orchestra: "Anibal Troilo", Year: "1963", Name: "Cumparsita", Query: "La cumparsita"})
+	discography := &Discography{
+		Tracks: []Track{
+			{Name: "La cumparsita", Year: "1951", Orchestra: "Anibal Troilo"},
+			{Name: "La cumparsita", Year: "1952", Orchestra: "Anibal Troilo"},
+			{Name: "Sin rumbo fijo", Year: "1938", Orchestra: "Orquesta Tipica Victor"},
+			{Name: "La cumparsita", Year: "1927", Orchestra: "Francisco Canaro"},
+			{Name: "Cumparsita", Year: "1963", Orchestra: "Anibal Troilo"},
+			{Name: "Milonga sentimental", Year: "1933", Orchestra: "Orquesta Tipica Victor"},
+		},
+	}
+	result, err := writeLocalPlaylist("Cumparsitas", discography, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 5 || result.NotFound != 1 || len(result.Tracks) != 6 {
+		t.Fatalf("expected 5 added and 1 missing track, got %+v", result)
+	}
+	if tr := result.Tracks[0]; !strings.HasSuffix(tr.ServiceID, filepath.Join("Troilo", "01.mp3")) {
+		t.Errorf("wrong file for track %+v", tr)
+	}
+	if tr := result.Tracks[1]; tr.Outcome != OutcomeNotFound {
+		t.Errorf("wrong outcome of missing track %+v", tr)
+	}
+	if tr := result.Tracks[4]; !strings.HasSuffix(tr.ServiceID, filepath.Join("Troilo", "02.mp3")) {
+		t.Errorf("wrong file for track with query override %+v", tr)
+	}
+	if tr := result.Tracks[5]; !strings.HasSuffix(tr.ServiceID, "Milonga.mp3") {
+		t.Errorf("wrong file for track of orchestra alias %+v", tr)
+	}
+
+	data, err := os.ReadFile(filepath.Join(odir, "Cumparsitas.m3u8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\r\n")
+	if len(lines) != 11 || lines[0] != "#EXTM3U" || lines[1] != "#EXTINF:-1,La Cumparsita - Aníbal Troilo" {
+		t.Errorf("wrong M3U content %q", lines)
+	}
+	data, err = os.ReadFile(filepath.Join(odir, "Cumparsitas-missing.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "1952") {
+		t.Errorf("wrong missing tracks %q", data)
+	}
+}
+
+func TestLocalPlaylistOverrides(t *testing.T) {
+	dir := t.TempDir()
+	Config.OutputDir = dir
+	trackOverrides = &Overrides{}
+	defer func() { Config = Configuration{}; trackOverrides = nil }()
+	trackOverrides.Add(Override{Orchestra: "Francisco Canaro", Year: "1935", Name: "Poema", Query: "Track 07"})
+	trackOverrides.Add(Override{Orchestra: "Anibal Troilo", Year: "1943", Name: "La cumparsita", Never: true})
+
+	files := []LibraryFile{
+		{Path: "/music/Canaro/Track 07.mp3", Title: "Track 07", Artist: "Francisco Canaro", Year: "1935"},
+		{Path: "/music/Troilo/La cumparsita.mp3", Title: "La cumparsita", Artist: "Anibal Troilo", Year: "1943"},
+	}
+	discography := &Discography{
+		Tracks: []Track{
+			{Name: "Poema", Year: "1935", Orchestra: "Francisco Canaro"},
+			{Name: "La cumparsita", Year: "1943", Orchestra: "Anibal Troilo"},
+		},
+	}
+	result, err := writeLocalPlaylist("Milonga", discography, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 1 || result.Skipped != 1 || result.NotFound != 0 {
+		t.Errorf("wrong build result %+v", result)
+	}
+	if tr := result.Tracks[0]; tr.ServiceID != files[0].Path || tr.Query != "Track 07" {
+		t.Errorf("wrong result of track with query override %+v", tr)
+	}
+	if tr := result.Tracks[1]; tr.Outcome != OutcomeNever {
+		t.Errorf("wrong outcome of track which is never added %+v", tr)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "Milonga.m3u8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "La cumparsita") || !strings.Contains(string(data), files[0].Path) {
+		t.Errorf("wrong M3U content %q", data)
+	}
+}
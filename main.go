@@ -1,7 +1,7 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -15,87 +15,94 @@ var spotifyId, spotifySecret, youtubeId, youtubSecret string
 // local cache
 var cache *Cache
 
-// Use the readXMLFile function
 func main() {
-	var file string
-	flag.StringVar(&file, "file", "", "xml or csv file to read")
-	var config string
-	flag.StringVar(&config, "config", "", "configuration file")
-	var title string
-	flag.StringVar(&title, "title", "", "title of new playlist")
-	var showTracks bool
-	flag.BoolVar(&showTracks, "tracks", false, "show tracks and exit")
-	var sortBy string
-	flag.StringVar(&sortBy, "sortBy", "", "sort tracks by attribute: orchestra, artist, year, genre, vocal")
-	var filterBy string
-	flag.StringVar(&filterBy, "filterBy", "", "filter tracks conditions: {key:value, key:value}")
-	var sortOrder string
-	flag.StringVar(&sortOrder, "sortOrder", "ascending", "sort order: ascending or descending")
-	flag.Parse()
-
-	err := parseConfig(config)
-	if err != nil {
-		log.Fatalf("Fail to parse config file %s, error %v", config, err)
+	args := os.Args[1:]
+	// load user defined aliases of orchestras and artists
+	if err := loadAliases(aliasesFile()); err != nil {
+		log.Fatal(err)
 	}
-	if Config.Verbose > 0 {
-		log.SetFlags(log.LstdFlags | log.Lshortfile)
+	// load manual matches of tracks which service search can not find
+	if err := loadOverrides(overridesFile()); err != nil {
+		log.Fatal(err)
 	}
-
-	var filters map[string]string
-	if filterBy != "" {
-		err := json.Unmarshal([]byte(filterBy), &filters)
-		if err != nil {
-			log.Fatal(err)
+	// keep legacy flag based invocation for existing scripts
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		legacyMain(args)
+		return
+	}
+	name := args[0]
+	if name == "help" {
+		if len(args) > 1 {
+			if cmd, ok := findCommand(args[1]); ok {
+				cmd.Run([]string{"-h"})
+				return
+			}
 		}
+		usage()
+		return
 	}
-
-	// by default use playlist title
-	ptitle := Config.PlaylistTitle
-	if ptitle == "" {
-		// if it is not parsed from input file we'll use name of the file itself
-		arr := strings.Split(file, "/")
-		if strings.HasSuffix(file, ".xml") {
-			ptitle = strings.Replace(arr[len(arr)-1], ".xml", "", -1)
-		} else if strings.HasSuffix(file, ".csv") {
-			ptitle = strings.Replace(arr[len(arr)-1], ".csv", "", -1)
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.Run(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
 		}
+		log.Fatalf("%s: %v", name, err)
+	}
+}
+
+// helper function to run goplaylist with legacy flags, where -tracks option
+// switches between showing tracks and uploading them to the service
+func legacyMain(args []string) {
+	var opts Options
+	fs := flag.NewFlagSet("goplaylist", flag.ExitOnError)
+	fs.Usage = func() {
+		usage()
+		fmt.Fprintf(os.Stderr, "\nLegacy options:\n")
+		fs.PrintDefaults()
 	}
-	if title != "" {
-		// use title provided via option
-		ptitle = title
+	fs.StringVar(&opts.File, "file", "", "xml or csv file to read")
+	fs.StringVar(&opts.Config, "config", "", "configuration file")
+	fs.StringVar(&opts.Title, "title", "", "title of new playlist")
+	fs.StringVar(&opts.Profile, "profile", "", "configuration profile to use")
+	opts.uploadFlags(fs)
+	opts.splitFlags(fs)
+	opts.dedupeFlags(fs)
+	var showTracks bool
+	fs.BoolVar(&showTracks, "tracks", false, "show tracks and exit")
+	fs.StringVar(&opts.SortBy, "sortBy", "", "sort tracks by attribute: orchestra, artist, year, genre, vocal, composer, author, label, matrix, duration, isrc")
+	fs.StringVar(&opts.FilterBy, "filterBy", "", "filter tracks conditions: {key:value, key:value}")
+	fs.StringVar(&opts.SortOrder, "sortOrder", "ascending", "sort order: ascending or descending")
+	fs.Parse(args)
+
+	if len(args) == 0 {
+		fs.Usage()
+		os.Exit(2)
 	}
-	if ptitle == "" {
-		log.Fatal("empty playlist title")
+
+	// config is only required when we talk to the service
+	if err := opts.loadConfig(!showTracks); err != nil {
+		log.Fatal(err)
 	}
-	fmt.Printf("creating %s playlist: %s\n", Config.Service, ptitle)
 
 	// if asked for tracks only, display them and exit
 	if showTracks {
 		Config.Verbose = 0
-		discography, _ := readFile(file, sortBy, sortOrder, filters)
-		discography.removeDuplicateTracks()
-		for idx, track := range discography.Tracks {
-			fmt.Printf("%4d %+v\n", idx, track)
+		discography, err := opts.readDiscography()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := opts.showTracks(discography); err != nil {
+			log.Fatal(err)
 		}
 		return
 	}
 
-	// read provided file
-	discography, err := readFile(file, sortBy, sortOrder, filters)
-	if err != nil {
-		log.Fatalf("Error reading XML file: %v", err)
-	}
-	discography.removeDuplicateTracks()
-
-	// choose a client to use
-	cache = &Cache{}
-	if strings.ToLower(Config.Service) == "spotify" {
-		cdir := fmt.Sprintf("%s/.goplaylist/%s", os.Getenv("HOME"), "spotify")
-		cache.Init("spotify", cdir)
-		setupSpotifyClient(ptitle, discography)
-	} else {
-		cdir := fmt.Sprintf("%s/.goplaylist/%s", os.Getenv("HOME"), "youtube")
-		cache.Init("youtube", cdir)
-		setupYouTubeService(ptitle, discography)
+	if err := runUpload(opts); err != nil {
+		log.Fatal(err)
 	}
 }
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/vkuznet/goplaylist/discography"
+)
+
+// helper function to connect to MPD server from configuration
+func mpdClient() (*MPDClient, error) {
+	address := Config.MPDAddress
+	if address == "" {
+		address = "localhost:6600"
+	}
+	client, err := NewMPDClient(address, Config.MPDPassword)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to MPD %s: %w", address, err)
+	}
+	log.Printf("Connected to MPD %s, protocol version %s", address, client.Version)
+	return client, nil
+}
+
+// helper function to build MPD stored playlist, it resolves file URIs of
+// discography tracks either from local cache or via MPD search, replaces
+// stored playlist with them and, if requested, loads it into the queue. It
+// returns outcome of the build along with outcome of every track.
+func buildMPDPlaylist(client *MPDClient, title string, disc *Discography, load bool) (BuildResult, error) {
+	result := BuildResult{Title: title, URL: title}
+	// MPD stored playlists are identified by their names
+	playlistID := title
+	cachedIDs, err := cache.LoadIDs(title, playlistID)
+	if err != nil {
+		log.Printf("unable to load cached file URIs for playlist '%s', error %v", title, err)
+	}
+	// look up cached tracks by their normalized keys
+	ids := make(map[string]string)
+	for trk, uri := range cachedIDs {
+		cached := discography.ParseTrack(trk)
+		ids[cached.Key()] = uri
+	}
+
+	// the stored playlist is replaced, therefore cached tracks are added again
+	var uris []string
+	ptracks := playlistTracks(title, disc)
+	bar := newProgressBar(title, len(ptracks))
+	defer bar.Finish()
+	for idx, trk := range ptracks {
+		// overrides exclude tracks or provide custom search query
+		var query string
+		override := trackOverrides.Lookup(trk)
+		if override != nil {
+			query = override.Query
+		}
+		tr := newTrackResult(idx, trk, trk.String())
+		if query != "" {
+			tr.Query = query
+		}
+		if override != nil && override.Never {
+			tr.Outcome = OutcomeNever
+			result.record(tr)
+			bar.Add(tr)
+			continue
+		}
+		uri, ok := ids[trk.Key()]
+		var err error
+		if !ok {
+			uri, err = client.SearchTrack(trk, query)
+			if err == nil {
+				cache.AddID(title, playlistID, trk, uri)
+			}
+		}
+		switch {
+		case errors.Is(err, errNotFound):
+			tr.Outcome, tr.Error = OutcomeNotFound, err.Error()
+		case err != nil:
+			tr.Outcome, tr.Error = OutcomeError, err.Error()
+		case ok:
+			// cached tracks are still added since playlist is replaced
+			tr.Outcome, tr.ServiceID = OutcomeCached, uri
+			uris = append(uris, uri)
+		default:
+			tr.Outcome, tr.ServiceID = OutcomeAdded, uri
+			uris = append(uris, uri)
+		}
+		result.record(tr)
+		bar.Add(tr)
+	}
+
+	if err := client.ReplacePlaylist(title, uris); err != nil {
+		return result, err
+	}
+	if load && len(uris) > 0 {
+		if _, err := client.Command("load", title); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// MPDClient represents client of Music Player Daemon
+type MPDClient struct {
+	Version string // protocol version reported by the server
+	conn    net.Conn
+	reader  *bufio.Reader
+}
+
+// MPDAttr represents single key-value pair of MPD response
+type MPDAttr struct {
+	Key   string
+	Value string
+}
+
+// NewMPDClient connects to MPD server, the address is either host:port or
+// path to unix socket
+func NewMPDClient(address, password string) (*MPDClient, error) {
+	network := "tcp"
+	if strings.HasPrefix(address, "/") || strings.HasPrefix(address, "@") {
+		network = "unix"
+	}
+	conn, err := net.DialTimeout(network, address, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	client := &MPDClient{conn: conn, reader: bufio.NewReader(conn)}
+	greeting, err := client.reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting, "OK MPD ") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected MPD greeting: %s", strings.TrimSpace(greeting))
+	}
+	client.Version = strings.TrimSpace(strings.TrimPrefix(greeting, "OK MPD "))
+	if password != "" {
+		if _, err := client.Command("password", password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// Close closes connection to MPD server
+func (m *MPDClient) Close() error {
+	return m.conn.Close()
+}
+
+// helper function to quote MPD command argument, line breaks are replaced
+// by spaces since they terminate MPD command
+func mpdQuote(arg string) string {
+	arg = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(arg)
+	arg = strings.ReplaceAll(arg, `\`, `\\`)
+	arg = strings.ReplaceAll(arg, `"`, `\"`)
+	return `"` + arg + `"`
+}
+
+// Command sends command with given arguments to MPD server and returns
+// list of attributes from its response
+func (m *MPDClient) Command(name string, args ...string) ([]MPDAttr, error) {
+	cmd := name
+	for _, arg := range args {
+		cmd += " " + mpdQuote(arg)
+	}
+	if _, err := m.conn.Write([]byte(cmd + "\n")); err != nil {
+		return nil, err
+	}
+	var attrs []MPDAttr
+	for {
+		line, err := m.reader.ReadString('\n')
+		if err != nil {
+			return attrs, err
+		}
+		line = strings.TrimRight(line, "\n")
+		if line == "OK" {
+			return attrs, nil
+		}
+		if strings.HasPrefix(line, "ACK ") {
+			return attrs, fmt.Errorf("MPD command '%s' failed: %s", name, strings.TrimPrefix(line, "ACK "))
+		}
+		if arr := strings.SplitN(line, ": ", 2); len(arr) == 2 {
+			attrs = append(attrs, MPDAttr{Key: arr[0], Value: arr[1]})
+		}
+	}
+}
+
+// helper function to group MPD attributes into songs, every song starts with file attribute
+func mpdSongs(attrs []MPDAttr) []map[string]string {
+	var songs []map[string]string
+	for _, attr := range attrs {
+		if attr.Key == "file" {
+			songs = append(songs, make(map[string]string))
+		}
+		if len(songs) > 0 {
+			songs[len(songs)-1][attr.Key] = attr.Value
+		}
+	}
+	return songs
+}
+
+// SearchTrack looks up files whose tags match track name, orchestra and year
+// and returns URI of the best match. We search by title and artist first and
+// fall back to the title alone since local files often tag orchestras in
+// different ways. Non empty query, e.g. the one of track overrides, is looked
+// up in any tag instead and its first result is taken unless other results
+// match the track better.
+func (m *MPDClient) SearchTrack(track Track, query string) (string, error) {
+	if query != "" {
+		attrs, err := m.Command("search", "any", query)
+		if err != nil {
+			return "", err
+		}
+		songs := mpdSongs(attrs)
+		if len(songs) == 0 {
+			return "", fmt.Errorf("%w for query: %s", errNotFound, query)
+		}
+		uri, bestScore := songs[0]["file"], 0
+		for _, song := range songs {
+			if score := mpdScore(song, track); score > bestScore {
+				uri, bestScore = song["file"], score
+			}
+		}
+		return uri, nil
+	}
+	attrs, err := m.Command("search", "title", track.Name, "artist", track.Orchestra)
+	if err != nil {
+		return "", err
+	}
+	songs := mpdSongs(attrs)
+	if len(songs) == 0 {
+		attrs, err = m.Command("search", "title", track.Name)
+		if err != nil {
+			return "", err
+		}
+		songs = mpdSongs(attrs)
+	}
+	var uri string
+	bestScore := 0
+	for _, song := range songs {
+		score := mpdScore(song, track)
+		if score > bestScore {
+			uri, bestScore = song["file"], score
+		}
+	}
+	if uri == "" {
+		return "", fmt.Errorf("%w for track: %s", errNotFound, track.String())
+	}
+	return uri, nil
+}
+
+// helper function to score MPD song against our track, either artist or
+// album artist of the song may match track orchestra, see songScore
+func mpdScore(song map[string]string, track Track) int {
+	return songScore(track, song["Title"], song["Date"], song["Artist"], song["AlbumArtist"])
+}
+
+// ReplacePlaylist creates or replaces stored playlist with given file URIs.
+// The files are added to temporary playlist which is renamed once all of
+// them are added, therefore existing playlist is kept if adding fails, and
+// it is not changed at all if there are no files.
+func (m *MPDClient) ReplacePlaylist(name string, uris []string) error {
+	if len(uris) == 0 {
+		log.Printf("no files are found for playlist '%s', it is not changed", name)
+		return nil
+	}
+	tmp := name + ".goplaylist-tmp"
+	// remove leftover of interrupted run, MPD replies with error if it does not exist
+	m.Command("rm", tmp)
+	for _, uri := range uris {
+		if _, err := m.Command("playlistadd", tmp, uri); err != nil {
+			m.Command("rm", tmp)
+			return err
+		}
+	}
+	if _, err := m.Command("rm", name); err != nil && Config.Verbose > 0 {
+		log.Printf("unable to remove playlist '%s': %v", name, err)
+	}
+	if _, err := m.Command("rename", tmp, name); err != nil {
+		return fmt.Errorf("unable to rename playlist '%s' to '%s': %w", tmp, name, err)
+	}
+	return nil
+}
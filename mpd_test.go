@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeMPD represents stand-in MPD server used in tests
+type fakeMPD struct {
+	songs     []map[string]string
+	playlists map[string][]string
+	queue     []string
+	searches  int
+}
+
+// helper function to split MPD command line into command and its arguments
+func splitMPDCommand(line string) []string {
+	var args []string
+	var cur strings.Builder
+	quoted, escaped := false, false
+	for _, r := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ' ' && !quoted:
+			args = append(args, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	return append(args, cur.String())
+}
+
+// helper function to serve single MPD connection
+func (f *fakeMPD) serve(conn net.Conn) {
+	defer conn.Close()
+	fmt.Fprint(conn, "OK MPD 0.23.5\n")
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		args := splitMPDCommand(scanner.Text())
+		switch args[0] {
+		case "search":
+			f.searches++
+			for _, song := range f.songs {
+				match := true
+				for i := 1; i+1 < len(args); i += 2 {
+					tags := []string{strings.ToUpper(args[i][:1]) + args[i][1:]}
+					if args[i] == "any" {
+						tags = []string{"Title", "Artist", "file"}
+					}
+					found := false
+					for _, tag := range tags {
+						found = found || strings.Contains(strings.ToLower(song[tag]), strings.ToLower(args[i+1]))
+					}
+					match = match && found
+				}
+				if match {
+					fmt.Fprintf(conn, "file: %s\n", song["file"])
+					for _, key := range []string{"Title", "Artist", "Date"} {
+						fmt.Fprintf(conn, "%s: %s\n", key, song[key])
+					}
+				}
+			}
+		case "rm":
+			if _, ok := f.playlists[args[1]]; !ok {
+				fmt.Fprint(conn, "ACK [50@0] {rm} No such playlist\n")
+				continue
+			}
+			delete(f.playlists, args[1])
+		case "rename":
+			if _, ok := f.playlists[args[2]]; ok {
+				fmt.Fprint(conn, "ACK [56@0] {rename} Playlist already exists\n")
+				continue
+			}
+			f.playlists[args[2]] = f.playlists[args[1]]
+			delete(f.playlists, args[1])
+		case "playlistadd":
+			f.playlists[args[1]] = append(f.playlists[args[1]], args[2])
+		case "load":
+			f.queue = append(f.queue, f.playlists[args[1]]...)
+		}
+		fmt.Fprint(conn, "OK\n")
+	}
+}
+
+// helper function to start fake MPD server, it returns path of its socket
+func startFakeMPD(t *testing.T, fake *fakeMPD) string {
+	socket := filepath.Join(t.TempDir(), "mpd.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			fake.serve(conn)
+		}
+	}()
+	return socket
+}
+
+func TestMPDPlaylist(t *testing.T) {
+	fake := &fakeMPD{
+		songs: []map[string]string{
+			{"file": "Troilo/La cumparsita 1943.mp3", "Title": "La cumparsita", "Artist": "Anibal Troilo", "Date": "1943"},
+			{"file": "Troilo/La cumparsita 1951.mp3", "Title": "La cumparsita", "Artist": "Anibal Troilo", "Date": "1951-01-01"},
+			{"file": "OTV/Sin rumbo fijo.mp3", "Title": "Sin rumbo fijo", "Artist": "OTV", "Date": "1938"},
+		},
+		playlists: map[string][]string{"Milonga": {"old.mp3"}},
+	}
+	socket := startFakeMPD(t, fake)
+
+	cache = &Cache{}
+	cache.Init("mpd", t.TempDir())
+
+	discography := &Discography{
+		Tracks: []Track{
+			{Name: "La cumparsita", Year: "1951", Orchestra: "Anibal Troilo"},
+			{Name: "Sin rumbo fijo", Year: "1938-04-18", Orchestra: "Orquesta Tipica Victor"},
+			{Name: "Unknown track", Year: "1930", Orchestra: "Unknown"},
+		},
+	}
+	expect := "Troilo/La cumparsita 1951.mp3,OTV/Sin rumbo fijo.mp3"
+	for i := 0; i < 2; i++ {
+		client, err := NewMPDClient(socket, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if client.Version != "0.23.5" {
+			t.Errorf("wrong protocol version %s", client.Version)
+		}
+		searches := fake.searches
+		result, err := buildMPDPlaylist(client, "Milonga", discography, i == 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.Close()
+		if got := strings.Join(fake.playlists["Milonga"], ","); got != expect {
+			t.Errorf("run %d: wrong playlist content %s, expected %s", i, got, expect)
+		}
+		// on second run resolved tracks should come from the cache
+		if i == 1 && fake.searches-searches != 2 {
+			t.Errorf("expected only unknown track to be searched, got %d searches", fake.searches-searches)
+		}
+		if added, cached := 2-2*i, 2*i; result.Added != added || result.Existing != cached || result.NotFound != 1 {
+			t.Errorf("run %d: wrong build result %+v", i, result)
+		}
+	}
+	if got := strings.Join(fake.queue, ","); got != expect {
+		t.Errorf("wrong queue content %s, expected %s", got, expect)
+	}
+}
+
+func TestMPDPlaylistOverrides(t *testing.T) {
+	fake := &fakeMPD{
+		songs: []map[string]string{
+			{"file": "Canaro/Track 07.mp3", "Title": "Track 07", "Artist": "Francisco Canaro", "Date": "1935"},
+			{"file": "Troilo/La cumparsita.mp3", "Title": "La cumparsita", "Artist": "Anibal Troilo", "Date": "1943"},
+		},
+		playlists: map[string][]string{},
+	}
+	socket := startFakeMPD(t, fake)
+	cache = &Cache{}
+	cache.Init("mpd", t.TempDir())
+	trackOverrides = &Overrides{}
+	defer func() { trackOverrides = nil }()
+	trackOverrides.Add(Override{Orchestra: "Francisco Canaro", Year: "1935", Name: "Poema", Query: "Track 07"})
+	trackOverrides.Add(Override{Orchestra: "Anibal Troilo", Year: "1943", Name: "La cumparsita", Never: true})
+
+	discography := &Discography{
+		Tracks: []Track{
+			{Name: "Poema", Year: "1935", Orchestra: "Francisco Canaro"},
+			{Name: "La cumparsita", Year: "1943", Orchestra: "Anibal Troilo"},
+		},
+	}
+	client, err := NewMPDClient(socket, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	result, err := buildMPDPlaylist(client, "Milonga", discography, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(fake.playlists["Milonga"], ","); got != "Canaro/Track 07.mp3" {
+		t.Errorf("wrong playlist content %s", got)
+	}
+	if result.Added != 1 || result.Skipped != 1 || result.Tracks[0].Query != "Track 07" || result.Tracks[1].Outcome != OutcomeNever {
+		t.Errorf("wrong build result %+v", result)
+	}
+	// track which is never added is not searched
+	if fake.searches != 1 {
+		t.Errorf("expected one search, got %d", fake.searches)
+	}
+}
+
+func TestMPDScore(t *testing.T) {
+	track := Track{Name: "Pájaro azul", Year: "1941-03-05", Orchestra: "Orquesta Típica Víctor"}
+	cases := []struct {
+		song  map[string]string
+		score int
+	}{
+		// accents, case and punctuation of titles are ignored
+		{map[string]string{"Title": "Pajaro Azul", "Artist": "Orquesta Tipica Victor", "Date": "1941"}, 4},
+		{map[string]string{"Title": "Pájaro azul - vals", "Artist": "Orquesta Tipica Victor", "Date": "1941-03-05"}, 3},
+		// orchestra alias of either artist or album artist matches its canonical name
+		{map[string]string{"Title": "pajaro azul", "Artist": "OTV", "Date": "1941"}, 4},
+		{map[string]string{"Title": "Pájaro azul", "Artist": "Lita Morales", "AlbumArtist": "Orq. Típica Víctor"}, 3},
+		{map[string]string{"Title": "Pájaro azul", "Artist": "Francisco Canaro", "Date": "1941"}, 3},
+		// title should contain all words of track name
+		{map[string]string{"Title": "Pájaro", "Artist": "OTV", "Date": "1941"}, 0},
+		{map[string]string{"Title": "Pájaros azules", "Artist": "OTV", "Date": "1941"}, 0},
+	}
+	for _, c := range cases {
+		if score := mpdScore(c.song, track); score != c.score {
+			t.Errorf("wrong score %d of %v, expected %d", score, c.song, c.score)
+		}
+	}
+}
+
+func TestMPDQuote(t *testing.T) {
+	cases := map[string]string{
+		`La cumparsita`:           `"La cumparsita"`,
+		`El "Pollito"`:            `"El \"Pollito\""`,
+		`C:\Music`:                `"C:\\Music"`,
+		"Poema\nclear\r\nlistall": `"Poema clear listall"`,
+	}
+	for arg, expect := range cases {
+		if quoted := mpdQuote(arg); quoted != expect {
+			t.Errorf("wrong quoting of %q: %s, expected %s", arg, quoted, expect)
+		}
+	}
+}
+
+func TestMPDReplacePlaylist(t *testing.T) {
+	fake := &fakeMPD{playlists: map[string][]string{"Milonga": {"old.mp3"}}}
+	client, err := NewMPDClient(startFakeMPD(t, fake), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// playlist is not removed if no files are found
+	if err := client.ReplacePlaylist("Milonga", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(fake.playlists["Milonga"], ","); got != "old.mp3" {
+		t.Errorf("playlist should not be changed, got %s", got)
+	}
+	if err := client.ReplacePlaylist("Milonga", []string{"a.mp3", "b.mp3"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(fake.playlists["Milonga"], ","); got != "a.mp3,b.mp3" || len(fake.playlists) != 1 {
+		t.Errorf("wrong playlists %v", fake.playlists)
+	}
+}
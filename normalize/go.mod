@@ -0,0 +1,5 @@
+module github.com/vkuznet/goplaylist/normalize
+
+go 1.23.3
+
+require golang.org/x/text v0.21.0
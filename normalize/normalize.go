@@ -0,0 +1,240 @@
+// Package normalize provides normalization of names found in tango
+// discographies: diacritic folding, punctuation aware tokenization and
+// canonical orchestra resolution. It is shared by goplaylist and its sister
+// tools, so "Ángel Vargas" and "Angel Vargas" are treated as the same name
+// everywhere.
+package normalize
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// apostrophes maps apostrophe variants to ASCII apostrophe
+var apostrophes = strings.NewReplacer("’", "'", "‘", "'", "`", "'", "´", "'", "ʼ", "'")
+
+// RemoveDiacritics removes diacritical marks from the string while keeping
+// its case, e.g. "Ángel D’Agostino" becomes "Angel D'Agostino"
+func RemoveDiacritics(s string) string {
+	var result strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if unicode.IsMark(r) {
+			continue // skip diacritical marks
+		}
+		result.WriteRune(r)
+	}
+	return apostrophes.Replace(norm.NFC.String(result.String()))
+}
+
+// Fold removes diacritics, converts the string to lower case and collapses
+// white spaces, e.g. " Ángel  Vargas" becomes "angel vargas"
+func Fold(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(RemoveDiacritics(s))), " ")
+}
+
+// Tokens splits folded string into tokens of letters and digits, all
+// punctuation separates tokens except apostrophes within words, e.g.
+// "Juan D'Arienzo y su Orq." gives [juan d'arienzo y su orq]
+func Tokens(s string) []string {
+	runes := []rune(Fold(s))
+	var tokens []string
+	var token []rune
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			token = append(token, r)
+			continue
+		case r == '\'' && len(token) > 0 && i+1 < len(runes) && unicode.IsLetter(runes[i+1]):
+			token = append(token, r)
+			continue
+		}
+		if len(token) > 0 {
+			tokens = append(tokens, string(token))
+			token = nil
+		}
+	}
+	if len(token) > 0 {
+		tokens = append(tokens, string(token))
+	}
+	return tokens
+}
+
+// Key returns comparison key of the name, names with the same key are
+// considered to be equal
+func Key(s string) string {
+	return strings.Join(Tokens(s), " ")
+}
+
+// Equal reports whether two names are equal after normalization
+func Equal(a, b string) bool {
+	return Key(a) == Key(b)
+}
+
+// Words returns words of the string in their original form, i.e. white
+// space separated tokens without surrounding punctuation which consist of
+// letters and apostrophes within them, e.g. "Juan D'Arienzo - 1935-1940"
+// gives [Juan D'Arienzo]
+func Words(s string) []string {
+	var words []string
+	for _, field := range strings.Fields(apostrophes.Replace(s)) {
+		word := strings.TrimFunc(field, func(r rune) bool { return !unicode.IsLetter(r) })
+		if word == "" {
+			continue
+		}
+		valid := true
+		for _, r := range word {
+			if !unicode.IsLetter(r) && r != '\'' {
+				valid = false
+				break
+			}
+		}
+		if valid {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// leaderSuffix matches suffix of the orchestra leader name, e.g. "Francisco
+// Canaro y su Orquesta Típica" or "Juan D'Arienzo y su Orq."
+var leaderSuffix = regexp.MustCompile(`(?i)\s+y\s+su\s+(orquesta|orq\b|conjunto|quinteto|sexteto|cuarteto|trio|trío).*$`)
+
+// Resolver resolves orchestra and artist name variants to their canonical
+// names and provides preferred spellings of canonical names to use in
+// searches of music services
+type Resolver struct {
+	orchestras map[string]string            // key of the variant to canonical orchestra name
+	artists    map[string]string            // key of the variant to canonical artist name
+	search     map[string]map[string]string // key of canonical name to service spellings
+}
+
+// NewResolver creates resolver from the map of canonical orchestra names to their variants
+func NewResolver(aliases map[string][]string) *Resolver {
+	r := &Resolver{
+		orchestras: make(map[string]string),
+		artists:    make(map[string]string),
+		search:     make(map[string]map[string]string),
+	}
+	for canonical, variants := range aliases {
+		r.AddOrchestra(canonical, variants...)
+	}
+	return r
+}
+
+// AddOrchestra registers variants of the canonical orchestra name
+func (r *Resolver) AddOrchestra(canonical string, variants ...string) {
+	r.orchestras[Key(canonical)] = canonical
+	for _, v := range variants {
+		r.orchestras[Key(v)] = canonical
+	}
+}
+
+// AddArtist registers variants of the canonical artist (e.g. vocalist) name
+func (r *Resolver) AddArtist(canonical string, variants ...string) {
+	r.artists[Key(canonical)] = canonical
+	for _, v := range variants {
+		r.artists[Key(v)] = canonical
+	}
+}
+
+// AddSearch registers preferred spelling of canonical name to use in
+// searches of given service
+func (r *Resolver) AddSearch(canonical, service, spelling string) {
+	key := Key(canonical)
+	if _, ok := r.search[key]; !ok {
+		r.search[key] = make(map[string]string)
+	}
+	r.search[key][strings.ToLower(service)] = spelling
+}
+
+// helper function to drop orchestra details, i.e. text in parentheses and
+// "y su Orquesta Típica" like suffixes
+func orchestraName(name string) string {
+	name = strings.TrimSpace(strings.Split(name, "(")[0])
+	return strings.TrimSpace(leaderSuffix.ReplaceAllString(name, ""))
+}
+
+// Orchestra returns canonical name of the orchestra, it drops details in
+// parentheses (e.g. "Francisco Canaro (Quinteto Pirincho)") and "y su
+// Orquesta Típica" like suffixes and resolves known aliases, unknown
+// orchestras are returned without these details
+func (r *Resolver) Orchestra(name string) string {
+	name = orchestraName(name)
+	if canonical, ok := r.orchestras[Key(name)]; ok {
+		return canonical
+	}
+	return name
+}
+
+// OrchestraKey returns comparison key of canonical name of the orchestra
+func (r *Resolver) OrchestraKey(name string) string {
+	return Key(r.Orchestra(name))
+}
+
+// OrchestraAlias returns canonical name of the orchestra if the name or
+// the name without details is a known alias, otherwise the name is
+// returned as is
+func (r *Resolver) OrchestraAlias(name string) string {
+	for _, n := range []string{name, orchestraName(name)} {
+		if canonical, ok := r.orchestras[Key(n)]; ok {
+			return canonical
+		}
+	}
+	return name
+}
+
+// KnownOrchestra reports whether the orchestra resolves to registered canonical name
+func (r *Resolver) KnownOrchestra(name string) bool {
+	_, ok := r.orchestras[Key(orchestraName(name))]
+	return ok
+}
+
+// Artist returns canonical name of the artist if it is a known alias,
+// otherwise the name is returned as is
+func (r *Resolver) Artist(name string) string {
+	if canonical, ok := r.artists[Key(name)]; ok {
+		return canonical
+	}
+	return name
+}
+
+// KnownArtist reports whether the artist resolves to registered canonical name
+func (r *Resolver) KnownArtist(name string) bool {
+	_, ok := r.artists[Key(name)]
+	return ok
+}
+
+// Search returns preferred spelling of the name to use in searches of
+// given service, the name is resolved to canonical orchestra or artist
+// name first and it is returned as is if no spelling is registered
+func (r *Resolver) Search(name, service string) string {
+	canonical := r.Orchestra(name)
+	if !r.KnownOrchestra(name) {
+		canonical = r.Artist(name)
+	}
+	if spelling, ok := r.search[Key(canonical)][strings.ToLower(service)]; ok {
+		return spelling
+	}
+	return canonical
+}
+
+// defaultAliases defines well known variants of orchestra names
+var defaultAliases = map[string][]string{
+	"Orquesta Tipica Victor": {"OTV", "Orq. Típica Víctor", "Típica Victor"},
+}
+
+// DefaultResolver is resolver with well known orchestra aliases
+var DefaultResolver = NewResolver(defaultAliases)
+
+// Orchestra returns canonical name of the orchestra using default resolver
+func Orchestra(name string) string {
+	return DefaultResolver.Orchestra(name)
+}
+
+// OrchestraKey returns comparison key of the orchestra using default resolver
+func OrchestraKey(name string) string {
+	return DefaultResolver.OrchestraKey(name)
+}
@@ -0,0 +1,86 @@
+package normalize
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFold(t *testing.T) {
+	for input, expect := range map[string]string{
+		" Ángel  Vargas":   "angel vargas",
+		"Raúl Berón":       "raul beron",
+		"Angel D’Agostino": "angel d'agostino",
+	} {
+		if got := Fold(input); got != expect {
+			t.Errorf("Fold(%q) = %q, expected %q", input, got, expect)
+		}
+	}
+	if !Equal("Ángel Vargas", "angel vargas") || !Equal("La Cumparsita.", "la cumparsita") {
+		t.Error("names should be equal after normalization")
+	}
+	if Equal("Ángel Vargas", "Angel D'Agostino") {
+		t.Error("different names should not be equal")
+	}
+}
+
+func TestTokens(t *testing.T) {
+	for input, expect := range map[string]string{
+		"Juan D'Arienzo y su Orq.":    "juan|d'arienzo|y|su|orq",
+		"Di Sarli - Vals (1940-1956)": "di|sarli|vals|1940|1956",
+		"'Rodolfo Biagi'":             "rodolfo|biagi",
+	} {
+		if got := strings.Join(Tokens(input), "|"); got != expect {
+			t.Errorf("Tokens(%q) = %q, expected %q", input, got, expect)
+		}
+	}
+	if got := strings.Join(Words("Juan D'Arienzo - Vals 1935-1940 (1/3)"), "|"); got != "Juan|D'Arienzo|Vals" {
+		t.Errorf("wrong words %q", got)
+	}
+}
+
+func TestOrchestra(t *testing.T) {
+	for input, expect := range map[string]string{
+		"Francisco Canaro (Quinteto Pirincho)": "Francisco Canaro",
+		"Juan D'Arienzo y su Orquesta Típica":  "Juan D'Arienzo",
+		"Orq. Típica Víctor":                   "Orquesta Tipica Victor",
+		"OTV":                                  "Orquesta Tipica Victor",
+		"Carlos Di Sarli":                      "Carlos Di Sarli",
+	} {
+		if got := Orchestra(input); got != expect {
+			t.Errorf("Orchestra(%q) = %q, expected %q", input, got, expect)
+		}
+	}
+	r := NewResolver(map[string][]string{"Francisco Canaro": {"Pirincho"}})
+	if r.OrchestraKey("pirincho") != "francisco canaro" {
+		t.Error("alias of custom resolver is not resolved")
+	}
+	// aliases keep details of unknown names
+	if got := r.OrchestraAlias("Pirincho (dir. Canaro)"); got != "Francisco Canaro" {
+		t.Errorf("wrong orchestra alias %q", got)
+	}
+	if got := r.OrchestraAlias("Quinteto Pirincho (dir. Canaro)"); got != "Quinteto Pirincho (dir. Canaro)" {
+		t.Errorf("unknown orchestra should be kept as is, got %q", got)
+	}
+}
+
+func TestResolver(t *testing.T) {
+	r := NewResolver(nil)
+	r.AddOrchestra("Orquesta Típica Victor", "OTV")
+	r.AddArtist("Ángel Vargas", "Angel Vargas", "A. Vargas")
+	r.AddSearch("Orquesta Típica Victor", "YouTube", "Orquesta Tipica Victor")
+	if got := r.Artist("a vargas"); got != "Ángel Vargas" {
+		t.Errorf("wrong canonical artist %q", got)
+	}
+	if !r.KnownArtist("ANGEL VARGAS") || r.KnownArtist("Jorge Durán") {
+		t.Error("wrong known artists")
+	}
+	if got := r.Search("OTV", "youtube"); got != "Orquesta Tipica Victor" {
+		t.Errorf("wrong youtube search spelling %q", got)
+	}
+	if got := r.Search("OTV", "spotify"); got != "Orquesta Típica Victor" {
+		t.Errorf("wrong spotify search spelling %q", got)
+	}
+	if got := r.Search("Angel Vargas", "spotify"); got != "Ángel Vargas" {
+		t.Errorf("wrong artist search spelling %q", got)
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Override represents manual match of discography track, the track is
+// identified either by its attributes or by its normalized key and is
+// mapped to explicit service item, custom search query or never added
+type Override struct {
+	Orchestra string `yaml:"orchestra,omitempty"`
+	Year      string `yaml:"year,omitempty"`
+	Name      string `yaml:"name,omitempty"`
+	Artist    string `yaml:"artist,omitempty"`
+	Key       string `yaml:"key,omitempty"`     // normalized key of the track, used instead of its attributes
+	Spotify   string `yaml:"spotify,omitempty"` // Spotify track URI, URL or ID
+	Youtube   string `yaml:"youtube,omitempty"` // YouTube video ID or URL
+	Query     string `yaml:"query,omitempty"`   // search query used instead of generated one
+	Never     bool   `yaml:"never,omitempty"`   // never add the track to playlists
+}
+
+// Overrides represents manual matches of tracks which service search can
+// not find
+type Overrides struct {
+	Tracks []Override     `yaml:"tracks"`
+	index  map[string]int // key of the track to its override
+}
+
+// trackOverrides holds manual matches consulted before service search
+var trackOverrides *Overrides
+
+// helper function to get location of overrides file, it can be changed
+// via GOPLAYLIST_OVERRIDES environment
+func overridesFile() string {
+	if fname := os.Getenv(envPrefix + "OVERRIDES"); fname != "" {
+		return fname
+	}
+	return filepath.Join(os.Getenv("HOME"), ".goplaylist", "overrides.yaml")
+}
+
+// helper function to get key of the track used to look up its override,
+// i.e. normalized key of the track with recording year instead of date
+func overrideKey(track Track) string {
+	if year := track.Year.YearString(); year != "" {
+		track.Year = RecordingDate(year)
+	}
+	return track.Key()
+}
+
+// helper function to get key of the override
+func (o *Override) key() string {
+	if o.Key != "" {
+		return o.Key
+	}
+	return overrideKey(Track{Orchestra: o.Orchestra, Year: RecordingDate(o.Year), Name: o.Name, Artist: o.Artist})
+}
+
+// helper function to read overrides file
+func readOverrides(fname string) (*Overrides, error) {
+	data, err := os.ReadFile(filepath.Clean(fname))
+	if err != nil {
+		return nil, err
+	}
+	var overrides Overrides
+	if err := yaml.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("unable to parse overrides file %s: %w", fname, err)
+	}
+	for idx, o := range overrides.Tracks {
+		if o.Key == "" && o.Name == "" {
+			return nil, fmt.Errorf("override %d of file %s has neither key nor name of the track", idx+1, fname)
+		}
+	}
+	overrides.reindex()
+	return &overrides, nil
+}
+
+// helper function to write overrides file
+func writeOverrides(fname string, overrides *Overrides) error {
+	if err := os.MkdirAll(filepath.Dir(fname), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(file)
+	enc.SetIndent(2)
+	err = enc.Encode(overrides)
+	if cerr := enc.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// helper function to load overrides file, it is not an error if the file
+// does not exist
+func loadOverrides(fname string) error {
+	overrides, err := readOverrides(fname)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	trackOverrides = overrides
+	return nil
+}
+
+// helper function to build index of overrides, the later entries win
+func (o *Overrides) reindex() {
+	o.index = make(map[string]int)
+	for idx := range o.Tracks {
+		o.index[o.Tracks[idx].key()] = idx
+	}
+}
+
+// Add appends override of the track
+func (o *Overrides) Add(override Override) {
+	o.Tracks = append(o.Tracks, override)
+	o.reindex()
+}
+
+// Lookup finds override of the track, overrides without artist match all
+// recordings of the track, it returns nil if there is no override
+func (o *Overrides) Lookup(track Track) *Override {
+	if o == nil {
+		return nil
+	}
+	if idx, ok := o.index[overrideKey(track)]; ok {
+		return &o.Tracks[idx]
+	}
+	track.Artist = ""
+	if idx, ok := o.index[overrideKey(track)]; ok {
+		return &o.Tracks[idx]
+	}
+	return nil
+}
+
+// helper function to apply explicit service IDs of the override to the track
+func (o *Override) apply(track *Track) {
+	if o.Spotify != "" {
+		track.SpotifyID = spotifyTrackID(o.Spotify)
+	}
+	if o.Youtube != "" {
+		track.YoutubeID = youtubeVideoID(o.Youtube)
+	}
+}
+
+// helper function to get action of the override in human readable form
+func (o *Override) action() string {
+	var actions []string
+	if o.Never {
+		actions = append(actions, "never add")
+	}
+	if o.Spotify != "" {
+		actions = append(actions, "spotify "+spotifyTrackID(o.Spotify))
+	}
+	if o.Youtube != "" {
+		actions = append(actions, "youtube "+youtubeVideoID(o.Youtube))
+	}
+	if o.Query != "" {
+		actions = append(actions, "query "+o.Query)
+	}
+	return strings.Join(actions, ", ")
+}
+
+// helper function to get Spotify track ID from its URI (spotify:track:ID),
+// URL (https://open.spotify.com/track/ID) or ID itself
+func spotifyTrackID(s string) string {
+	s = strings.TrimSpace(s)
+	if id, ok := strings.CutPrefix(s, "spotify:track:"); ok {
+		return id
+	}
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		if id, ok := strings.CutPrefix(u.Path, "/track/"); ok {
+			return id
+		}
+	}
+	return s
+}
+
+// helper function to get YouTube video ID from its URL, e.g.
+// https://www.youtube.com/watch?v=ID or https://youtu.be/ID, or ID itself
+func youtubeVideoID(s string) string {
+	s = strings.TrimSpace(s)
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return s
+	}
+	if id := u.Query().Get("v"); id != "" {
+		return id
+	}
+	return strings.TrimPrefix(u.Path, "/")
+}
+
+// overridesCommand lists manual matches of tracks, "overrides add" adds
+// tracks of the report which service search did not find
+func overridesCommand(args []string) error {
+	if len(args) > 0 && args[0] == "add" {
+		return overridesAddCommand(args[1:])
+	}
+	var fname string
+	fs := newFlagSet("overrides", "List manual matches of tracks, use \"goplaylist overrides add\" to add tracks of the report.")
+	fs.StringVar(&fname, "file", overridesFile(), "overrides file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	overrides, err := readOverrides(fname)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TRACK\tACTION")
+	for _, o := range overrides.Tracks {
+		track := o.Key
+		if track == "" {
+			track = strings.Join([]string{o.Orchestra, o.Year, o.Name, o.Artist}, ", ")
+		}
+		fmt.Fprintf(w, "%s\t%s\n", track, o.action())
+	}
+	return w.Flush()
+}
+
+// helper function to add tracks of the report with given outcomes to
+// overrides file, the entries get query which was used by the search and
+// should be edited to provide service IDs or better query
+func overridesAddCommand(args []string) error {
+	var fname, report, outcomes string
+	var never bool
+	fs := newFlagSet("overrides add", "Add tracks of the report which service search did not find to overrides file.\n"+
+		"The entries get query used by the search, edit them to provide spotify or youtube IDs or better query.")
+	fs.StringVar(&fname, "file", overridesFile(), "overrides file")
+	fs.StringVar(&report, "report", "", "json or csv report of upload or batch command")
+	fs.StringVar(&outcomes, "outcome", OutcomeNotFound, "comma separated outcomes of tracks to add, e.g. not-found,low-confidence")
+	fs.BoolVar(&never, "never", false, "mark added tracks to be never added to playlists")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if report == "" {
+		return errors.New("no report file is provided, please use -report option")
+	}
+	tracks, err := readReportTracks(report)
+	if err != nil {
+		return err
+	}
+	overrides, err := readOverrides(fname)
+	if errors.Is(err, os.ErrNotExist) {
+		overrides = &Overrides{}
+	} else if err != nil {
+		return err
+	}
+	selected := make(map[string]bool)
+	for _, outcome := range strings.Split(outcomes, ",") {
+		selected[strings.TrimSpace(outcome)] = true
+	}
+	var added int
+	for _, tr := range tracks {
+		if !selected[tr.Outcome] {
+			continue
+		}
+		track := Track{Orchestra: tr.Orchestra, Year: RecordingDate(tr.Year), Name: tr.Name, Artist: tr.Artist}
+		if overrides.Lookup(track) != nil {
+			continue
+		}
+		overrides.Add(Override{Orchestra: tr.Orchestra, Year: tr.Year, Name: tr.Name, Artist: tr.Artist, Query: tr.Query, Never: never})
+		added++
+	}
+	if added == 0 {
+		fmt.Println("no new tracks found in report", report)
+		return nil
+	}
+	if err := writeOverrides(fname, overrides); err != nil {
+		return err
+	}
+	fmt.Printf("%d tracks are added to %s\n", added, fname)
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOverrides(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "overrides.yaml")
+	data := `tracks:
+  - orchestra: Orquesta Típica Victor
+    year: 1938
+    name: Sin rumbo fijo
+    youtube: https://www.youtube.com/watch?v=abc123
+  - key: "francisco canaro|1935|la cumparsita|"
+    query: La cumparsita Canaro 1935 original
+  - orchestra: Francisco Canaro
+    year: 1930
+    name: Vuelve la serenata
+    artist: Ernesto Fama
+    never: true
+`
+	if err := os.WriteFile(fname, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	overrides, err := readOverrides(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tracks are matched by normalized attributes, recording year and key
+	o := overrides.Lookup(Track{Orchestra: "Orquesta Tipica Victor", Year: "1938-04-18", Name: "Sin Rumbo Fijo", Artist: "Roberto Díaz"})
+	if o == nil || youtubeVideoID(o.Youtube) != "abc123" {
+		t.Errorf("unexpected override %+v", o)
+	}
+	if o := overrides.Lookup(Track{Orchestra: "Francisco Canaro", Year: "1935", Name: "La Cumparsita"}); o == nil || o.Query == "" {
+		t.Errorf("override is not found by its key: %+v", o)
+	}
+	if o := overrides.Lookup(Track{Orchestra: "Francisco Canaro", Year: "1930", Name: "Vuelve la serenata"}); o != nil {
+		t.Errorf("override with artist should not match other recordings: %+v", o)
+	}
+	if o := overrides.Lookup(Track{Orchestra: "Francisco Canaro", Year: "1931", Name: "Sin rumbo fijo"}); o != nil {
+		t.Errorf("unexpected override %+v", o)
+	}
+	var missing *Overrides
+	if o := missing.Lookup(Track{Name: "Sin rumbo fijo"}); o != nil {
+		t.Errorf("unexpected override without overrides file %+v", o)
+	}
+
+	if err := os.WriteFile(fname, []byte("tracks:\n  - youtube: abc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readOverrides(fname); err == nil {
+		t.Error("expected error of override without track")
+	}
+}
+
+func TestServiceItemIDs(t *testing.T) {
+	for _, s := range []string{"spotify:track:4uLU6hMCjMI75M1A2tKUQC", "https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC?si=x", "4uLU6hMCjMI75M1A2tKUQC"} {
+		if id := spotifyTrackID(s); id != "4uLU6hMCjMI75M1A2tKUQC" {
+			t.Errorf("wrong spotify ID %s of %s", id, s)
+		}
+	}
+	for _, s := range []string{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=1", "https://youtu.be/dQw4w9WgXcQ", "dQw4w9WgXcQ"} {
+		if id := youtubeVideoID(s); id != "dQw4w9WgXcQ" {
+			t.Errorf("wrong youtube ID %s of %s", id, s)
+		}
+	}
+}
+
+func TestBuildPlaylistOverrides(t *testing.T) {
+	cache = &Cache{}
+	cache.Init("youtube", t.TempDir())
+	trackOverrides = &Overrides{}
+	defer func() { trackOverrides = nil }()
+	trackOverrides.Add(Override{Orchestra: "Francisco Canaro", Year: "1930", Name: "First", Youtube: "https://youtu.be/vid1"})
+	trackOverrides.Add(Override{Orchestra: "Francisco Canaro", Year: "1931", Name: "Second", Query: "custom query"})
+	trackOverrides.Add(Override{Orchestra: "Francisco Canaro", Year: "1932", Name: "Third", Never: true})
+
+	discography := &Discography{Orchestra: "Francisco Canaro", Tracks: []Track{
+		{Name: "First", Year: "1930"},
+		{Name: "Second", Year: "1931"},
+		{Name: "Third", Year: "1932"},
+		{Name: "Fourth", Year: "1933"},
+	}}
+	provider := &fakeProvider{name: "youtube"}
+	result, err := buildPlaylist(provider, "Canaro", discography, PlaylistOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 3 || result.Skipped != 1 || result.Tracks[2].Outcome != OutcomeNever {
+		t.Errorf("wrong build result %+v", result)
+	}
+	if strings.Join(provider.items, ",") != "vid1,id-custom query,id-Fourth" {
+		t.Errorf("wrong playlist items %v", provider.items)
+	}
+	// track with explicit service ID is not searched
+	if strings.Join(provider.queries, ",") != "custom query,Fourth" {
+		t.Errorf("wrong search queries %v", provider.queries)
+	}
+}
+
+func TestOverridesAddCommand(t *testing.T) {
+	dir := t.TempDir()
+	report := filepath.Join(dir, "report.csv")
+	var result BuildResult
+	result.record(TrackResult{Index: 1, Orchestra: "Francisco Canaro", Year: "1930", Name: "First", Outcome: OutcomeAdded})
+	result.record(TrackResult{Index: 2, Orchestra: "Francisco Canaro", Year: "1931", Name: "Second", Outcome: OutcomeNotFound, Query: "Second Canaro"})
+	result.record(TrackResult{Index: 3, Orchestra: "Francisco Canaro", Year: "1932", Name: "Third", Outcome: OutcomeLowConfidence})
+	if err := writeReport(report, RunReport{Playlists: []PlaylistReport{{Service: "youtube", BuildResult: result}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	fname := filepath.Join(dir, "overrides.yaml")
+	for i := 0; i < 2; i++ {
+		// the second run does not add tracks which are already present
+		if err := overridesCommand([]string{"add", "-file", fname, "-report", report, "-outcome", "not-found,low-confidence"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	overrides, err := readOverrides(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overrides.Tracks) != 2 || overrides.Tracks[0].Query != "Second Canaro" || overrides.Tracks[1].Name != "Third" {
+		t.Errorf("unexpected overrides %+v", overrides.Tracks)
+	}
+	if o := overrides.Lookup(Track{Orchestra: "Francisco Canaro", Year: "1931", Name: "Second"}); o == nil {
+		t.Error("added override is not found")
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+
+	"github.com/vkuznet/goplaylist/normalize"
+)
+
+// Provider represents music service which holds our playlists
+type Provider interface {
+	// Name returns name of the service, it is used to separate cache areas
+	Name() string
+	// FindPlaylist returns ID of existing playlist with given title
+	FindPlaylist(title string) (string, error)
+	// CreatePlaylist creates new playlist with given title, description and
+	// privacy (public, private or unlisted) and returns its ID
+	CreatePlaylist(title, description, privacy string) (string, error)
+	// Query constructs service specific search query for given track
+	Query(track Track) string
+	// Search looks up given query and returns the best matching item, it
+	// returns error wrapping errNotFound if nothing matches the query
+	Search(query string, track Track) (Match, error)
+	// AddItem adds item with given ID to the playlist, the original
+	// discography track can be used to annotate the playlist item
+	AddItem(playlistID, itemID string, track Track) error
+	// PlaylistURL returns URL of the playlist
+	PlaylistURL(playlistID string) string
+}
+
+// Match represents item of the service found for discography track
+type Match struct {
+	ID    string // service ID of the item
+	Title string // title of the item as shown by the service, e.g. "Name - Artist"
+	Score int    // how well the item matches the track, from 0 to 100
+}
+
+// errNotFound is reported by providers when no items match the query
+var errNotFound = errors.New("no matching items found")
+
+// helper function to score text of found item (e.g. its title and artists)
+// against the track, words of track name give 70 points and words of the
+// orchestra give 30 points, tracks without orchestra are scored by name only
+func matchScore(track Track, text string) int {
+	words := make(map[string]bool)
+	for _, token := range normalize.Tokens(text) {
+		words[token] = true
+	}
+	// helper function to get fraction of tokens found in the text
+	found := func(s string) float64 {
+		tokens := normalize.Tokens(s)
+		if len(tokens) == 0 {
+			return 0
+		}
+		var n int
+		for _, token := range tokens {
+			if words[token] {
+				n++
+			}
+		}
+		return float64(n) / float64(len(tokens))
+	}
+	if normalize.Key(track.Orchestra) == "" {
+		return int(math.Round(100 * found(track.Name)))
+	}
+	return int(math.Round(70*found(track.Name) + 30*found(track.Orchestra)))
+}
+
+// helper function to score tagged song of music library (MPD or Subsonic)
+// against the track, song title should contain all words of track name,
+// while exact title, matching orchestra and recording year increase the
+// score. Names are compared after normalization and orchestras after their
+// aliases are resolved, e.g. "OTV" matches "Orquesta Típica Víctor".
+func songScore(track Track, title, date string, artists ...string) int {
+	name := normalize.Key(track.Name)
+	title = normalize.Key(title)
+	if name == "" || !containsWords(title, name) {
+		return 0
+	}
+	score := 1
+	if title == name {
+		score++
+	}
+	if orchestra := normalize.OrchestraKey(track.Orchestra); orchestra != "" {
+		for _, artist := range artists {
+			if containsWords(normalize.OrchestraKey(artist), orchestra) {
+				score++
+				break
+			}
+		}
+	}
+	if year := track.Year.YearString(); year != "" && strings.HasPrefix(date, year) {
+		score++
+	}
+	return score
+}
+
+// helper function to check if normalized key contains all words of another
+// key in the same order, e.g. "la cumparsita 1943" contains "la cumparsita"
+func containsWords(key, words string) bool {
+	return strings.Contains(" "+key+" ", " "+words+" ")
+}
+
+// maxPlaylistSizes defines maximum number of tracks in a playlist per service
+var maxPlaylistSizes = map[string]int{
+	"youtube": 5000,
+	"spotify": 10000,
+}
+
+// helper function to get maximum number of tracks in a playlist of given
+// service, the configuration may lower the service limit, zero means
+// playlist size is unlimited
+func maxPlaylistSize(service string) int {
+	size := maxPlaylistSizes[service]
+	if Config.MaxPlaylistSize > 0 && (size == 0 || Config.MaxPlaylistSize < size) {
+		size = Config.MaxPlaylistSize
+	}
+	return size
+}
+
+// helper function to split playlist into parts which fit given size, the
+// parts get "Title (1/3)" like titles and are filled in order of tracks,
+// therefore every re-run with the same tracks lands them in the same part
+func playlistParts(spec PlaylistSpec, size int) []PlaylistSpec {
+	tracks := spec.Discography.Tracks
+	if size <= 0 || len(tracks) <= size {
+		return []PlaylistSpec{spec}
+	}
+	nparts := (len(tracks) + size - 1) / size
+	orchestra := spec.Discography.Orchestra
+	var parts []PlaylistSpec
+	for i := 0; i < nparts; i++ {
+		end := min((i+1)*size, len(tracks))
+		// the part inherits orchestra of preceding tracks
+		discography := &Discography{Orchestra: orchestra, Tracks: tracks[i*size : end]}
+		for _, track := range discography.Tracks {
+			if track.Orchestra != "" {
+				orchestra = track.Orchestra
+			}
+		}
+		parts = append(parts, PlaylistSpec{
+			Title:       fmt.Sprintf("%s (%d/%d)", spec.Title, i+1, nparts),
+			Discography: discography,
+		})
+	}
+	return parts
+}
+
+// helper function to prepare discography tracks for service lookup, i.e.
+// every track gets canonical name of an orchestra (either its own, the one
+// from previous tracks, or the one obtained from playlist title or
+// discography), recording year without month and day, and its ISRC and
+// service IDs used for exact lookups
+func playlistTracks(title string, discography *Discography) []Track {
+	// obtain orchestra either from title of discography
+	orchestra := getOrchestra(title, discography)
+	var tracks []Track
+	for _, track := range discography.Tracks {
+		if track.Orchestra != "" {
+			orchestra = track.Orchestra
+		}
+		year := RecordingDate(track.Year.YearString())
+		trk := Track{Name: track.Name, Year: year, Orchestra: normalize.Orchestra(orchestra), Artist: track.Artist,
+			ISRC: track.ISRC, SpotifyID: track.SpotifyID, YoutubeID: track.YoutubeID}
+		tracks = append(tracks, trk)
+	}
+	return tracks
+}
+
+// BuildResult represents outcome of building a playlist
+type BuildResult struct {
+	Title         string        `json:"title"`            // title of the playlist
+	URL           string        `json:"url"`              // URL (or file name) of the playlist
+	Added         int           `json:"added"`            // number of tracks added to the playlist
+	Existing      int           `json:"existing"`         // number of tracks which were already in the playlist
+	NotFound      int           `json:"notFound"`         // number of tracks not found in the service
+	LowConfidence int           `json:"lowConfidence"`    // number of tracks whose matches score below min_score
+	Skipped       int           `json:"skipped"`          // number of tracks marked to be never added in overrides file
+	Failed        int           `json:"failed"`           // number of tracks which service failed to add
+	Tracks        []TrackResult `json:"tracks,omitempty"` // outcome of every track
+}
+
+// Summary returns one line summary of the build
+func (r BuildResult) Summary() string {
+	return fmt.Sprintf("playlist '%s': %d added, %d already exist, %d not found, %d low confidence, %d never added, %d failed",
+		r.Title, r.Added, r.Existing, r.NotFound, r.LowConfidence, r.Skipped, r.Failed)
+}
+
+// helper function to record outcome of the track and update counters
+func (r *BuildResult) record(tr TrackResult) {
+	switch tr.Outcome {
+	case OutcomeAdded:
+		r.Added++
+	case OutcomeCached:
+		r.Existing++
+	case OutcomeNotFound:
+		r.NotFound++
+	case OutcomeLowConfidence:
+		r.LowConfidence++
+	case OutcomeNever:
+		r.Skipped++
+	case OutcomeError:
+		r.Failed++
+	}
+	r.Tracks = append(r.Tracks, tr)
+}
+
+// errQuotaExhausted is reported when session quota of service searches is used up
+var errQuotaExhausted = errors.New("quota of service searches is exhausted")
+
+// Quota represents budget of service searches shared by all playlists
+// built within the session, zero limit means unlimited budget
+type Quota struct {
+	Limit int
+	Used  int
+}
+
+// Spend uses one search from the budget, nil quota is unlimited
+func (q *Quota) Spend() error {
+	if q == nil || q.Limit <= 0 {
+		return nil
+	}
+	if q.Used >= q.Limit {
+		return errQuotaExhausted
+	}
+	q.Used++
+	return nil
+}
+
+// Exhausted reports if there are no searches left in the budget
+func (q *Quota) Exhausted() bool {
+	return q != nil && q.Limit > 0 && q.Used >= q.Limit
+}
+
+// helper function to build playlist with given provider, it creates
+// playlist if necessary, adds all discography tracks which are not yet
+// present in local cache and returns outcome of the build along with
+// outcome of every track, the service searches are taken from given quota
+// (nil quota is unlimited) and matches which score below min_score of
+// configuration are not added. Overrides of the tracks are consulted before
+// the search, they provide service IDs, custom queries or exclude tracks.
+// Given options define privacy, description and cover of the playlist.
+func buildPlaylist(p Provider, title string, discography *Discography, opts PlaylistOptions, quota *Quota) (BuildResult, error) {
+	result := BuildResult{Title: title}
+	// check if playlist already exist, if not we will create it
+	playlistID, err := p.FindPlaylist(title)
+	created := false
+	if err != nil {
+		log.Printf("Unable to lookup playlist ID for '%s', error %v", title, err)
+		playlistID, err = p.CreatePlaylist(title, playlistDescription(title, discography, opts.Description), opts.Privacy)
+		if err != nil {
+			return result, fmt.Errorf("unable to create %s playlist '%s': %w", p.Name(), title, err)
+		}
+		created = true
+	}
+	result.URL = p.PlaylistURL(playlistID)
+
+	// set cover image of new playlist, cover of existing one is replaced
+	// only if it is requested explicitly
+	if created || opts.ReplaceCover {
+		uploadCover(p, playlistID, title, discography, opts.Cover)
+	}
+
+	// load cache entries for our playlist
+	tracks, err := cache.Load(p.Name(), title, playlistID)
+	if err != nil {
+		log.Printf("unable to find tracks for playlist '%s' (%v), error %v", title, playlistID, err)
+	}
+
+	ptracks := playlistTracks(title, discography)
+	bar := newProgressBar(title, len(ptracks))
+	defer bar.Finish()
+	for idx, trk := range ptracks {
+		query := p.Query(trk)
+		override := trackOverrides.Lookup(trk)
+		if override != nil {
+			override.apply(&trk)
+			if override.Query != "" {
+				query = override.Query
+			}
+		}
+		tr := newTrackResult(idx, trk, query)
+		if override != nil && override.Never {
+			tr.Outcome = OutcomeNever
+			result.record(tr)
+			bar.Add(tr)
+			continue
+		}
+		if inList(trk, tracks) {
+			tr.Outcome = OutcomeCached
+			result.record(tr)
+			bar.Add(tr)
+			continue
+		}
+		// tracks with known service ID do not require search
+		match := Match{ID: trk.ServiceID(p.Name()), Score: 100}
+		var err error
+		if match.ID == "" {
+			if err := quota.Spend(); err != nil {
+				return result, err
+			}
+			match, err = p.Search(tr.Query, trk)
+		}
+		tr.ServiceID, tr.MatchedTitle, tr.Score = match.ID, match.Title, match.Score
+		switch {
+		case errors.Is(err, errNotFound):
+			tr.Outcome, tr.Error = OutcomeNotFound, err.Error()
+		case err != nil:
+			tr.Outcome, tr.Error = OutcomeError, err.Error()
+		case match.Score < Config.MinScore:
+			tr.Outcome = OutcomeLowConfidence
+		default:
+			if err := p.AddItem(playlistID, match.ID, discography.Tracks[idx]); err != nil {
+				tr.Outcome, tr.Error = OutcomeError, err.Error()
+				break
+			}
+			// add track to local cache if was successfully added to playlist
+			cache.AddTrack(title, playlistID, trk)
+			tr.Outcome = OutcomeAdded
+		}
+		result.record(tr)
+		bar.Add(tr)
+	}
+	return result, nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// outcomes of playlist tracks
+const (
+	OutcomeAdded         = "added"          // track is added to the playlist
+	OutcomeCached        = "skipped-cached" // track is already in the playlist according to local cache
+	OutcomeNotFound      = "not-found"      // service search did not find the track
+	OutcomeLowConfidence = "low-confidence" // found item scores below min_score and is not added
+	OutcomeError         = "error"          // service failed to search or add the track
+	OutcomeNever         = "skipped-never"  // track is marked to be never added in overrides file
+)
+
+// TrackResult represents outcome of a track when playlist is built
+type TrackResult struct {
+	Index        int    `json:"index"` // position of the track in the playlist starting from 1
+	Orchestra    string `json:"orchestra"`
+	Year         string `json:"year"`
+	Name         string `json:"name"`
+	Artist       string `json:"artist,omitempty"`
+	Outcome      string `json:"outcome"`
+	ServiceID    string `json:"serviceId,omitempty"`    // service ID of the matched item
+	MatchedTitle string `json:"matchedTitle,omitempty"` // title of the matched item as shown by the service
+	Score        int    `json:"score"`                  // score of the match from 0 to 100
+	Query        string `json:"query,omitempty"`        // search query of the track
+	Error        string `json:"error,omitempty"`
+}
+
+// helper function to create result of the playlist track with given index
+func newTrackResult(idx int, track Track, query string) TrackResult {
+	return TrackResult{
+		Index:     idx + 1,
+		Orchestra: track.Orchestra,
+		Year:      string(track.Year),
+		Name:      track.Name,
+		Artist:    track.Artist,
+		Query:     query,
+	}
+}
+
+// PlaylistReport represents outcome of the playlist built within the run
+type PlaylistReport struct {
+	Service string `json:"service"`
+	Error   string `json:"error,omitempty"`
+	BuildResult
+}
+
+// RunReport represents outcome of all playlists built within the run
+type RunReport struct {
+	Started   time.Time        `json:"started"`
+	Finished  time.Time        `json:"finished"`
+	Playlists []PlaylistReport `json:"playlists"`
+}
+
+// helper function to check format of report file, it is defined by file
+// extension and can be either json or csv
+func reportFormat(fname string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(fname)); ext {
+	case ".json", ".csv":
+		return ext[1:], nil
+	}
+	return "", fmt.Errorf("unsupported format of report file %s, should be json or csv", fname)
+}
+
+// helper function to write report of the run into given file
+func writeReport(fname string, report RunReport) error {
+	format, err := reportFormat(fname)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	if format == "csv" {
+		err = writeReportCSV(file, report)
+	} else {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// reportHeader defines columns of CSV report
+var reportHeader = []string{"service", "playlist", "index", "orchestra", "year", "name", "artist",
+	"outcome", "service_id", "matched_title", "score", "query", "error"}
+
+// helper function to write CSV report with one row per track
+func writeReportCSV(w io.Writer, report RunReport) error {
+	writer := csv.NewWriter(w)
+	writer.Write(reportHeader)
+	for _, playlist := range report.Playlists {
+		for _, tr := range playlist.Tracks {
+			writer.Write([]string{playlist.Service, playlist.Title, strconv.Itoa(tr.Index), tr.Orchestra, tr.Year,
+				tr.Name, tr.Artist, tr.Outcome, tr.ServiceID, tr.MatchedTitle, strconv.Itoa(tr.Score), tr.Query, tr.Error})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// helper function to read tracks of the report written by writeReport
+func readReportTracks(fname string) ([]TrackResult, error) {
+	format, err := reportFormat(fname)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if format == "json" {
+		var report RunReport
+		if err := json.NewDecoder(file).Decode(&report); err != nil {
+			return nil, fmt.Errorf("unable to parse report %s: %w", fname, err)
+		}
+		var tracks []TrackResult
+		for _, playlist := range report.Playlists {
+			tracks = append(tracks, playlist.Tracks...)
+		}
+		return tracks, nil
+	}
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse report %s: %w", fname, err)
+	}
+	if len(records) == 0 || len(records[0]) != len(reportHeader) {
+		return nil, fmt.Errorf("unexpected header of report %s", fname)
+	}
+	var tracks []TrackResult
+	for _, record := range records[1:] {
+		if len(record) != len(reportHeader) {
+			continue
+		}
+		index, _ := strconv.Atoi(record[2])
+		score, _ := strconv.Atoi(record[10])
+		tracks = append(tracks, TrackResult{Index: index, Orchestra: record[3], Year: record[4], Name: record[5],
+			Artist: record[6], Outcome: record[7], ServiceID: record[8], MatchedTitle: record[9], Score: score,
+			Query: record[11], Error: record[12]})
+	}
+	return tracks, nil
+}
+
+// helper function to print summary table of built playlists
+func printSummary(w io.Writer, playlists []PlaylistReport) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tPLAYLIST\tADDED\tCACHED\tNOT FOUND\tLOW CONFIDENCE\tNEVER\tERRORS\tSTATUS")
+	var total BuildResult
+	for _, p := range playlists {
+		status := p.URL
+		if p.Error != "" {
+			status = "error: " + strings.ReplaceAll(p.Error, "\n", "; ")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%s\n",
+			p.Service, p.Title, p.Added, p.Existing, p.NotFound, p.LowConfidence, p.Skipped, p.Failed, status)
+		total.Added += p.Added
+		total.Existing += p.Existing
+		total.NotFound += p.NotFound
+		total.LowConfidence += p.LowConfidence
+		total.Skipped += p.Skipped
+		total.Failed += p.Failed
+	}
+	if len(playlists) > 1 {
+		fmt.Fprintf(tw, "\tTOTAL\t%d\t%d\t%d\t%d\t%d\t%d\t\n",
+			total.Added, total.Existing, total.NotFound, total.LowConfidence, total.Skipped, total.Failed)
+	}
+	tw.Flush()
+}
+
+// progressBar shows progress of playlist build on terminal, with verbose
+// configuration every track is printed instead
+type progressBar struct {
+	w      io.Writer // nil if progress is not drawn
+	title  string
+	total  int
+	done   int
+	counts map[string]int
+}
+
+// helper function to create progress bar of playlist with given number of
+// tracks, the bar is drawn only if standard error is a terminal
+func newProgressBar(title string, total int) *progressBar {
+	bar := &progressBar{title: title, total: total, counts: make(map[string]int)}
+	if info, err := os.Stderr.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 && Config.Verbose == 0 {
+		bar.w = os.Stderr
+	}
+	return bar
+}
+
+// Add records outcome of the track and redraws the bar
+func (b *progressBar) Add(tr TrackResult) {
+	b.done++
+	b.counts[tr.Outcome]++
+	if Config.Verbose > 0 {
+		msg := fmt.Sprintf("idx: %4d %-14s query: %s", tr.Index, tr.Outcome, tr.Query)
+		if tr.Error != "" {
+			msg += ", " + tr.Error
+		}
+		fmt.Println(msg)
+	}
+	b.draw()
+}
+
+// Finish completes the bar
+func (b *progressBar) Finish() {
+	if b.w != nil && b.done > 0 {
+		fmt.Fprintln(b.w)
+	}
+}
+
+// helper function to draw the bar
+func (b *progressBar) draw() {
+	if b.w == nil || b.total == 0 {
+		return
+	}
+	const width = 30
+	// more outcomes than expected tracks must not overflow the bar
+	filled := min(width*b.done/b.total, width)
+	fmt.Fprintf(b.w, "\r%s [%s%s] %d/%d added %d, cached %d, not found %d, low confidence %d, never %d, errors %d",
+		b.title, strings.Repeat("#", filled), strings.Repeat("-", width-filled), b.done, b.total,
+		b.counts[OutcomeAdded], b.counts[OutcomeCached], b.counts[OutcomeNotFound],
+		b.counts[OutcomeLowConfidence], b.counts[OutcomeNever], b.counts[OutcomeError])
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatchScore(t *testing.T) {
+	track := Track{Name: "Bahía Blanca", Orchestra: "Carlos Di Sarli"}
+	tests := []struct {
+		text  string
+		score int
+	}{
+		{"Bahia Blanca - Carlos Di Sarli", 100},
+		{"Bahía Blanca (Remastered) Carlos Di Sarli y su Orquesta Típica", 100},
+		{"Bahia Blanca - Osvaldo Pugliese", 70},
+		{"Blanca - Carlos Di Sarli", 65},
+		{"Nada - Osvaldo Pugliese", 0},
+	}
+	for _, test := range tests {
+		if score := matchScore(track, test.text); score != test.score {
+			t.Errorf("score of %q: expected %d, got %d", test.text, test.score, score)
+		}
+	}
+	if score := matchScore(Track{Name: "Bahía Blanca"}, "Bahia Blanca"); score != 100 {
+		t.Errorf("track without orchestra should be scored by name, got %d", score)
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	var result BuildResult
+	result.Title = "Valses"
+	result.record(TrackResult{Index: 1, Name: "Sin rumbo fijo", Outcome: OutcomeAdded, ServiceID: "id1", Score: 100})
+	result.record(TrackResult{Index: 2, Name: "Lagrimitas", Outcome: OutcomeCached})
+	result.record(TrackResult{Index: 3, Name: "Vuelve la serenata", Outcome: OutcomeNotFound, Query: "Vuelve la serenata"})
+	if result.Added != 1 || result.Existing != 1 || result.NotFound != 1 || len(result.Tracks) != 3 {
+		t.Fatalf("wrong build result %+v", result)
+	}
+	run := RunReport{Playlists: []PlaylistReport{{Service: "spotify", BuildResult: result}}}
+
+	dir := t.TempDir()
+	if err := writeReport(filepath.Join(dir, "report.txt"), run); err == nil {
+		t.Error("expected error of unsupported report format")
+	}
+	fname := filepath.Join(dir, "report.json")
+	if err := writeReport(fname, run); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report RunReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Playlists) != 1 || report.Playlists[0].Title != "Valses" || len(report.Playlists[0].Tracks) != 3 {
+		t.Errorf("wrong json report %+v", report)
+	}
+
+	fname = filepath.Join(dir, "report.csv")
+	if err := writeReport(fname, run); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 || strings.Join(records[3], ",") != "spotify,Valses,3,,,Vuelve la serenata,,not-found,,,0,Vuelve la serenata," {
+		t.Errorf("wrong csv report %v", records)
+	}
+
+	var summary strings.Builder
+	printSummary(&summary, run.Playlists)
+	if lines := strings.Split(strings.TrimSpace(summary.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[1], "Valses") {
+		t.Errorf("wrong summary\n%s", summary.String())
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	var buf bytes.Buffer
+	bar := &progressBar{w: &buf, title: "Canaro", total: 2, counts: make(map[string]int)}
+	for i := 0; i < 3; i++ {
+		bar.Add(TrackResult{Index: i + 1, Outcome: OutcomeAdded})
+	}
+	bar.Finish()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\r")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, "Canaro ["+strings.Repeat("#", 30)+"] 3/2 added 3") {
+		t.Errorf("unexpected progress bar %q", last)
+	}
+}
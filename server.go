@@ -0,0 +1,599 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vkuznet/goplaylist/discography"
+)
+
+//go:embed index.html
+var indexHTML embed.FS
+
+// ServerJob represents playlist build job submitted to API server, jobs
+// are executed one at a time in order of their submission
+type ServerJob struct {
+	ID        int              `json:"id"`
+	Job       Job              `json:"job"`
+	Service   string           `json:"service"`
+	State     string           `json:"state"` // queued, running, done or failed
+	Error     string           `json:"error,omitempty"`
+	Playlists []PlaylistStatus `json:"playlists"`
+	Events    []ServerEvent    `json:"events"`
+	specs     []PlaylistSpec
+	changed   chan struct{} // closed and replaced on every change of the job
+}
+
+// PlaylistStatus represents progress of playlist built by the job
+type PlaylistStatus struct {
+	Title  string       `json:"title"`
+	Tracks int          `json:"tracks"`
+	State  string       `json:"state"` // queued, running, done or failed
+	Error  string       `json:"error,omitempty"`
+	Result *BuildResult `json:"result,omitempty"`
+}
+
+// ServerEvent represents progress event of the job
+type ServerEvent struct {
+	Time    time.Time `json:"time"`
+	State   string    `json:"state"`
+	Message string    `json:"message"`
+}
+
+// DiscographyFile represents discography file available to API server
+type DiscographyFile struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// PlaylistPreview represents tracks of the playlist shown before its build
+type PlaylistPreview struct {
+	Title  string   `json:"title"`
+	Tracks []Track  `json:"tracks"`
+	Status []string `json:"status,omitempty"` // status of tracks in local cache, see Cache.TrackStatus
+}
+
+// JobRequest represents job submitted to API server, it may list tracks of
+// its playlists explicitly (e.g. previews which were reordered or where some
+// tracks were excluded), in such case discography file of the job is not read
+type JobRequest struct {
+	Job
+	Playlists []PlaylistPreview `json:"playlists,omitempty"`
+}
+
+// Server represents goplaylist HTTP API server, it keeps discography files
+// in its directory and builds playlists with sessions which are opened (and
+// authorized) once per service
+type Server struct {
+	Dir      string           // directory of discography files
+	callback *callbackHandler // receiver of OAuth callbacks
+	base     Configuration    // configuration the jobs are applied on top of
+	open     func(service string, opts SessionOptions) (Session, error)
+	sessions map[string]Session
+	current  *ServerJob // job which is being executed
+	mu       sync.Mutex
+	jobs     []*ServerJob
+	queue    chan *ServerJob
+	done     chan struct{}
+}
+
+// maxQueuedJobs defines how many jobs may wait for execution, new jobs are
+// rejected once the queue is full
+const maxQueuedJobs = 100
+
+// helper function to create API server for discography files of given
+// directory and start execution of its jobs
+func newServer(dir string) *Server {
+	s := &Server{
+		Dir:      dir,
+		callback: &callbackHandler{},
+		base:     Config,
+		open:     openSession,
+		sessions: make(map[string]Session),
+		queue:    make(chan *ServerJob, maxQueuedJobs),
+		done:     make(chan struct{}),
+	}
+	// playlist title of configuration is not applied to jobs, see Job.config
+	s.base.PlaylistTitle = ""
+	Config.PlaylistTitle = ""
+	s.callback.notify = func(service, authURL string) {
+		if s.current != nil {
+			s.update(s.current, nil, "%s authorization is required, please visit %s", service, authURL)
+		}
+	}
+	go s.run()
+	return s
+}
+
+// Close waits for submitted jobs and closes sessions of the server
+func (s *Server) Close() error {
+	close(s.queue)
+	<-s.done
+	for _, session := range s.sessions {
+		session.Close()
+	}
+	return nil
+}
+
+// Handler provides HTTP handler of API server endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.indexHandler)
+	mux.HandleFunc("GET /api/files", s.filesHandler)
+	mux.HandleFunc("POST /api/files", s.uploadHandler)
+	mux.HandleFunc("GET /api/tracks", s.tracksHandler)
+	mux.HandleFunc("GET /api/jobs", s.jobsHandler)
+	mux.HandleFunc("POST /api/jobs", s.submitHandler)
+	mux.HandleFunc("GET /api/jobs/{id}", s.jobHandler)
+	mux.HandleFunc("GET /api/jobs/{id}/events", s.eventsHandler)
+	mux.HandleFunc("GET /api/auth", s.authHandler)
+	mux.Handle("GET /callback", s.callback)
+	return mux
+}
+
+// helper function to write JSON response
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("unable to write response: %v", err)
+	}
+}
+
+// helper function to write error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// helper function to resolve name of discography file (or glob pattern)
+// within server directory, names can not point outside of it
+func (s *Server) path(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("no file is provided")
+	}
+	return filepath.Join(s.Dir, filepath.Clean("/"+name)), nil
+}
+
+// helper function to read discography of the job and construct its playlists
+func (s *Server) playlists(job *Job) ([]PlaylistSpec, error) {
+	fname, err := s.path(job.File)
+	if err != nil {
+		return nil, err
+	}
+	opts := job.options()
+	opts.File = fname
+	discography, err := opts.loadDiscography(job.FilterBy)
+	if err != nil {
+		return nil, err
+	}
+	return opts.playlists(discography)
+}
+
+// helper function to get job parameters from URL query, filterBy
+// parameter holds filters in JSON, e.g. {"genre":"vals"}
+func queryJob(query url.Values) (Job, error) {
+	job := Job{
+		File:      query.Get("file"),
+		Title:     query.Get("title"),
+		SortBy:    query.Get("sortBy"),
+		SortOrder: query.Get("sortOrder"),
+		Service:   query.Get("service"),
+		SplitBy:   query.Get("splitBy"),
+		Dedupe:    query.Get("dedupe"),
+	}
+	if filterBy := query.Get("filterBy"); filterBy != "" {
+		if err := json.Unmarshal([]byte(filterBy), &job.FilterBy); err != nil {
+			return job, fmt.Errorf("unable to parse filterBy '%s': %w", filterBy, err)
+		}
+	}
+	return job, nil
+}
+
+// indexHandler serves web page to preview, filter and publish playlists
+func (s *Server) indexHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := indexHTML.ReadFile("index.html")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(data)
+}
+
+// filesHandler lists discography files of server directory
+func (s *Server) filesHandler(w http.ResponseWriter, r *http.Request) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	files := []DiscographyFile{}
+	for _, entry := range entries {
+		// skip hidden files, e.g. temporary files of uploads in progress
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || (ext != ".xml" && ext != ".csv") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, DiscographyFile{Name: entry.Name(), Size: info.Size(), Modified: info.ModTime()})
+	}
+	writeJSON(w, http.StatusOK, files)
+}
+
+// uploadHandler stores discography files of multipart form (file field)
+// in server directory, the files are checked before they are stored
+func (s *Server) uploadHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	headers := r.MultipartForm.File["file"]
+	if len(headers) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("no file is provided in file field of the form"))
+		return
+	}
+	var names []string
+	for _, header := range headers {
+		name := filepath.Base(header.Filename)
+		if ext := filepath.Ext(name); ext != ".xml" && ext != ".csv" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported file format of %s, should be xml or csv", name))
+			return
+		}
+		src, err := header.Open()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		err = s.store(src, name)
+		src.Close()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		names = append(names, name)
+	}
+	writeJSON(w, http.StatusCreated, map[string][]string{"files": names})
+}
+
+// helper function to store uploaded file in server directory, the file is
+// written under temporary hidden name until it is known to be a valid
+// discography, and the temporary file is removed if upload fails
+func (s *Server) store(src io.Reader, name string) error {
+	tmp, err := os.CreateTemp(s.Dir, ".upload-*"+filepath.Ext(name))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if _, err := discography.ReadFile(tmp.Name()); err != nil {
+		return fmt.Errorf("invalid discography file %s: %w", name, err)
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.Dir, name))
+}
+
+// tracksHandler shows tracks of the playlists which will be built from
+// discography file with given filterBy, sortBy and other job parameters
+// along with status of the tracks in local cache of the service
+func (s *Server) tracksHandler(w http.ResponseWriter, r *http.Request) {
+	job, err := queryJob(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	specs, err := s.playlists(&job)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	opts := job.options()
+	service := opts.serviceName()
+	pcache := &Cache{Dir: cacheDir(service)}
+	previews := []PlaylistPreview{}
+	for _, spec := range specs {
+		preview := PlaylistPreview{Title: spec.Title, Tracks: spec.Discography.Tracks}
+		preview.Status, err = pcache.TrackStatus(service, spec.Title, playlistTracks(spec.Title, spec.Discography))
+		if err != nil {
+			log.Printf("unable to find status of tracks of playlist '%s': %v", spec.Title, err)
+		}
+		previews = append(previews, preview)
+	}
+	writeJSON(w, http.StatusOK, previews)
+}
+
+// helper function to construct playlists from tracks given by the client,
+// playlists which exceed limit of the service are split into parts
+func requestPlaylists(previews []PlaylistPreview, service string) ([]PlaylistSpec, error) {
+	var specs []PlaylistSpec
+	for _, preview := range previews {
+		if preview.Title == "" || len(preview.Tracks) == 0 {
+			return nil, errors.New("playlists of the job should have title and tracks")
+		}
+		spec := PlaylistSpec{Title: preview.Title, Discography: &Discography{Tracks: preview.Tracks}}
+		specs = append(specs, playlistParts(spec, maxPlaylistSize(service))...)
+	}
+	return specs, nil
+}
+
+// submitHandler validates job given in JSON body and queues it
+func (s *Server) submitHandler(w http.ResponseWriter, r *http.Request) {
+	var req JobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unable to parse job: %w", err))
+		return
+	}
+	job := req.Job
+	if job.Cover != "" && job.Cover != "auto" {
+		// only cover images of server directory can be used
+		cover, err := s.path(job.Cover)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		job.Cover = cover
+	}
+	opts := job.options()
+	service := opts.serviceName()
+	config := job.config(s.base)
+	if err := config.Validate(service); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var specs []PlaylistSpec
+	var err error
+	if len(req.Playlists) > 0 {
+		specs, err = requestPlaylists(req.Playlists, service)
+	} else {
+		specs, err = s.playlists(&job)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	sjob := &ServerJob{Job: job, Service: service, State: "queued", specs: specs, changed: make(chan struct{})}
+	for _, spec := range specs {
+		sjob.Playlists = append(sjob.Playlists, PlaylistStatus{Title: spec.Title, Tracks: len(spec.Discography.Tracks), State: "queued"})
+	}
+	// the job is recorded only if it fits the queue, its first event is
+	// added before the worker may pick it up
+	message := fmt.Sprintf("job is queued, %d playlists to build", len(specs))
+	s.mu.Lock()
+	sjob.ID = len(s.jobs) + 1
+	sjob.Events = append(sjob.Events, ServerEvent{Time: time.Now(), State: sjob.State, Message: message})
+	select {
+	case s.queue <- sjob:
+		s.jobs = append(s.jobs, sjob)
+	default:
+		s.mu.Unlock()
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("too many jobs are queued, please try again later"))
+		return
+	}
+	s.mu.Unlock()
+	log.Printf("job %d: %s", sjob.ID, message)
+	writeJSON(w, http.StatusAccepted, s.snapshot(sjob))
+}
+
+// helper function to find job by ID of request path
+func (s *Server) job(r *http.Request) (*ServerJob, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil || id < 1 || id > len(s.jobs) {
+		return nil, fmt.Errorf("job %s is not found", r.PathValue("id"))
+	}
+	return s.jobs[id-1], nil
+}
+
+// jobsHandler lists all jobs of the server
+func (s *Server) jobsHandler(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	jobs := make([]*ServerJob, len(s.jobs))
+	copy(jobs, s.jobs)
+	s.mu.Unlock()
+	snapshots := []ServerJob{}
+	for _, job := range jobs {
+		snapshots = append(snapshots, s.snapshot(job))
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].ID > snapshots[j].ID })
+	writeJSON(w, http.StatusOK, snapshots)
+}
+
+// jobHandler shows status and results of the job
+func (s *Server) jobHandler(w http.ResponseWriter, r *http.Request) {
+	job, err := s.job(r)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.snapshot(job))
+}
+
+// eventsHandler streams progress events of the job as server-sent events,
+// the stream ends with "done" event which holds final state of the job
+func (s *Server) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	job, err := s.job(r)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	var sent int
+	for {
+		s.mu.Lock()
+		events := job.Events[sent:]
+		finished := job.State == "done" || job.State == "failed"
+		changed := job.changed
+		s.mu.Unlock()
+		for _, event := range events {
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(w, "event: progress\ndata: %s\n\n", data)
+		}
+		sent += len(events)
+		if finished {
+			data, _ := json.Marshal(s.snapshot(job))
+			fmt.Fprintf(w, "event: done\ndata: %s\n\n", data)
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// authHandler shows pending authorization of the service, a client should
+// send user to its URL, the service then redirects user to callback endpoint
+func (s *Server) authHandler(w http.ResponseWriter, r *http.Request) {
+	service, authURL := s.callback.pending()
+	writeJSON(w, http.StatusOK, map[string]string{"service": service, "url": authURL})
+}
+
+// helper function to get copy of the job which is safe to encode
+func (s *Server) snapshot(job *ServerJob) ServerJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := *job
+	snapshot.Playlists = append([]PlaylistStatus{}, job.Playlists...)
+	snapshot.Events = append([]ServerEvent{}, job.Events...)
+	return snapshot
+}
+
+// helper function to change the job and record progress event, waiting
+// streams of job events are notified about the change
+func (s *Server) update(job *ServerJob, change func(), format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	s.mu.Lock()
+	if change != nil {
+		change()
+	}
+	job.Events = append(job.Events, ServerEvent{Time: time.Now(), State: job.State, Message: message})
+	close(job.changed)
+	job.changed = make(chan struct{})
+	s.mu.Unlock()
+	log.Printf("job %d: %s", job.ID, message)
+}
+
+// helper function to execute submitted jobs one at a time, since they
+// share configuration and local cache of the service
+func (s *Server) run() {
+	defer close(s.done)
+	for job := range s.queue {
+		s.current = job
+		s.runJob(job)
+		s.current = nil
+	}
+}
+
+// helper function to get session of the service, it is opened once and
+// asks for all scopes which jobs may need
+func (s *Server) session(service string) (Session, error) {
+	if session, ok := s.sessions[service]; ok {
+		return session, nil
+	}
+	opts := SessionOptions{Privacies: []string{"public", "private"}, Cover: true}
+	session, err := s.open(service, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.sessions[service] = session
+	return session, nil
+}
+
+// helper function to build playlists of the job
+func (s *Server) runJob(job *ServerJob) {
+	s.update(job, func() { job.State = "running" }, "job is started")
+	// playlist parameters of the job are applied on top of server
+	// configuration and passed to the session of the service
+	config := job.Job.config(s.base)
+	opts := config.playlistOptions()
+	opts.ReplaceCover = job.Job.Cover != ""
+
+	session, err := s.session(job.Service)
+	if err != nil {
+		s.update(job, func() { job.State, job.Error = "failed", err.Error() }, "unable to open %s session: %v", job.Service, err)
+		return
+	}
+	var failed int
+	for idx, spec := range job.specs {
+		status := &job.Playlists[idx]
+		s.update(job, func() { status.State = "running" }, "creating %s playlist: %s", job.Service, spec.Title)
+		result, err := session.Build(spec.Title, spec.Discography, opts)
+		if err != nil {
+			failed++
+			s.update(job, func() { status.State, status.Error, status.Result = "failed", err.Error(), &result },
+				"couldn't build playlist '%s': %v", spec.Title, err)
+			continue
+		}
+		s.update(job, func() { status.State, status.Result = "done", &result }, "%s", result.Summary())
+	}
+	if failed > 0 {
+		err := fmt.Errorf("%d of %d playlists failed", failed, len(job.specs))
+		s.update(job, func() { job.State, job.Error = "failed", err.Error() }, "job is failed: %v", err)
+		return
+	}
+	s.update(job, func() { job.State = "done" }, "job is done")
+}
+
+// serveCommand runs HTTP API server which builds playlists on behalf of
+// web tools, the server also provides web page to use the API
+func serveCommand(args []string) error {
+	var opts Options
+	var dir, host string
+	fs := newFlagSet("serve", "Run HTTP API server and web page to upload discography files, preview their tracks and build playlists.\n"+
+		"The server listens on callback_port of configuration and receives OAuth callbacks of services.\n"+
+		"The API has no authentication, therefore by default it is only available on this machine.")
+	fs.StringVar(&opts.Config, "config", "", "configuration file")
+	fs.StringVar(&opts.Profile, "profile", "", "configuration profile to use")
+	fs.StringVar(&dir, "dir", ".", "directory of discography files, uploaded files are stored there")
+	fs.StringVar(&host, "host", "127.0.0.1", "address to listen on, e.g. 0.0.0.0 exposes unauthenticated API to the network")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := opts.loadConfig(true); err != nil {
+		return err
+	}
+	if Config.CallbackPort <= 0 || Config.CallbackPort > 65535 {
+		return fmt.Errorf("invalid callback_port %d", Config.CallbackPort)
+	}
+	server := newServer(dir)
+	defer server.Close()
+	oauthCallbacks = server.callback
+	addr := net.JoinHostPort(host, strconv.Itoa(Config.CallbackPort))
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		log.Printf("WARNING: goplaylist API on %s has no authentication, anyone who can reach it may upload files and build playlists", addr)
+	}
+	log.Printf("goplaylist is available at http://%s", addr)
+	return http.ListenAndServe(addr, server.Handler())
+}
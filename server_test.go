@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeProvider implements Provider interface, tracks of "Vuelve la
+// serenata" are never found
+type fakeProvider struct {
+	name      string // name of the service, default is fake
+	items     []string
+	queries   []string
+	privacies map[string]string // privacy of created playlists
+}
+
+func (f *fakeProvider) Name() string {
+	if f.name != "" {
+		return f.name
+	}
+	return "fake"
+}
+
+func (f *fakeProvider) FindPlaylist(title string) (string, error) {
+	if _, ok := f.privacies[title]; !ok {
+		return "", fmt.Errorf("playlist %s is not found", title)
+	}
+	return "playlist-" + title, nil
+}
+
+func (f *fakeProvider) CreatePlaylist(title, description, privacy string) (string, error) {
+	if f.privacies == nil {
+		f.privacies = make(map[string]string)
+	}
+	f.privacies[title] = privacy
+	return "playlist-" + title, nil
+}
+
+func (f *fakeProvider) Query(track Track) string {
+	return track.Name
+}
+
+func (f *fakeProvider) PlaylistURL(playlistID string) string {
+	return "https://fake/" + playlistID
+}
+
+func (f *fakeProvider) Search(query string, track Track) (Match, error) {
+	f.queries = append(f.queries, query)
+	if query == "Vuelve la serenata" {
+		return Match{}, fmt.Errorf("%w for query: %s", errNotFound, query)
+	}
+	return Match{ID: "id-" + query, Title: track.Name, Score: 70}, nil
+}
+
+func (f *fakeProvider) AddItem(playlistID, itemID string, track Track) error {
+	f.items = append(f.items, itemID)
+	return nil
+}
+
+// helper function to upload file to API server
+func uploadFile(t *testing.T, server *httptest.Server, name string, data []byte) *http.Response {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	form.Close()
+	resp, err := http.Post(server.URL+"/api/files", form.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestServer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	Config = Configuration{SubsonicURL: "http://localhost", SubsonicUser: "dj", SubsonicPassword: "secret"}
+	defer func() { Config = Configuration{} }()
+
+	provider := &fakeProvider{}
+	dir := t.TempDir()
+	s := newServer(dir)
+	s.open = func(service string, opts SessionOptions) (Session, error) {
+		return newProviderSession(provider, opts.Quota), nil
+	}
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	// web page is served at the root
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var page bytes.Buffer
+	page.ReadFrom(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(page.String(), "/api/tracks") {
+		t.Errorf("unexpected web page %s", resp.Status)
+	}
+
+	// upload discography files, invalid ones are rejected
+	data, err := os.ReadFile("testplaylist.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := uploadFile(t, server, "testplaylist.xml", data); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("unexpected status of upload %s", resp.Status)
+	}
+	if resp := uploadFile(t, server, "bad.xml", []byte("<discography><track")); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid file should be rejected, got %s", resp.Status)
+	}
+	// temporary files of uploads are removed and never listed
+	if matches, _ := filepath.Glob(filepath.Join(dir, ".upload-*")); len(matches) != 0 {
+		t.Errorf("temporary upload files are not removed %v", matches)
+	}
+	os.WriteFile(filepath.Join(dir, ".upload-123.xml"), data, 0644)
+	var files []DiscographyFile
+	getJSON(t, server.URL+"/api/files", &files)
+	if len(files) != 1 || files[0].Name != "testplaylist.xml" {
+		t.Errorf("unexpected files %+v", files)
+	}
+
+	// preview tracks with filters and sorting
+	var previews []PlaylistPreview
+	query := url.Values{"file": {"testplaylist.xml"}, "filterBy": {`{"genre":"vals"}`}, "sortBy": {"year"}, "title": {"Valses"}}
+	getJSON(t, server.URL+"/api/tracks?"+query.Encode(), &previews)
+	if len(previews) != 1 || previews[0].Title != "Valses" || len(previews[0].Tracks) != 2 || previews[0].Tracks[0].Name != "Sin rumbo fijo" {
+		t.Errorf("unexpected preview %+v", previews)
+	}
+	if len(previews) == 1 && strings.Join(previews[0].Status, ",") != "new,new" {
+		t.Errorf("unexpected status of preview tracks %v", previews[0].Status)
+	}
+	resp, err = http.Get(server.URL + "/api/tracks?file=../../etc/passwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("files outside of server directory should not be read, got %s", resp.Status)
+	}
+
+	// submit job and stream its progress
+	job := `{"file": "testplaylist.xml", "title": "Valses", "filterBy": {"genre": "vals"}, "service": "subsonic", "privacy": "private"}`
+	resp, err = http.Post(server.URL+"/api/jobs", "application/json", strings.NewReader(job))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var submitted ServerJob
+	json.NewDecoder(resp.Body).Decode(&submitted)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || submitted.ID != 1 || len(submitted.Playlists) != 1 {
+		t.Fatalf("unexpected submitted job %s %+v", resp.Status, submitted)
+	}
+	resp, err = http.Get(fmt.Sprintf("%s/api/jobs/%d/events", server.URL, submitted.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	var done ServerJob
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			events = append(events, line)
+		}
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok && events[len(events)-1] == "done" {
+			json.Unmarshal([]byte(data), &done)
+		}
+	}
+	resp.Body.Close()
+	if len(events) < 2 || events[len(events)-1] != "done" {
+		t.Errorf("unexpected events %v", events)
+	}
+	result := done.Playlists[0].Result
+	if done.State != "done" || result == nil || result.Added != 1 || result.NotFound != 1 {
+		t.Errorf("unexpected job result %+v", done)
+	}
+	if len(provider.items) != 1 || provider.items[0] != "id-Sin rumbo fijo" {
+		t.Errorf("unexpected playlist items %v", provider.items)
+	}
+	// privacy of the job is applied to its playlists only
+	if provider.privacies["Valses"] != "private" || Config.Privacy != "" {
+		t.Errorf("unexpected privacy of playlist %v, configuration %q", provider.privacies, Config.Privacy)
+	}
+
+	// invalid jobs are rejected before they are queued
+	resp, err = http.Post(server.URL+"/api/jobs", "application/json", strings.NewReader(`{"file": "testplaylist.xml", "service": "spotify"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid job should be rejected, got %s", resp.Status)
+	}
+
+	// tracks of playlists may be given explicitly, e.g. reordered preview
+	job = `{"service": "subsonic", "playlists": [{"title": "Reordered", "tracks": [
+		{"Orchestra": "Francisco Canaro", "Year": "1940", "Name": "Second"},
+		{"Orchestra": "Francisco Canaro", "Year": "1930", "Name": "First"}]}]}`
+	resp, err = http.Post(server.URL+"/api/jobs", "application/json", strings.NewReader(job))
+	if err != nil {
+		t.Fatal(err)
+	}
+	submitted = ServerJob{}
+	json.NewDecoder(resp.Body).Decode(&submitted)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || len(submitted.Playlists) != 1 || submitted.Playlists[0].Title != "Reordered" || submitted.Playlists[0].Tracks != 2 {
+		t.Fatalf("unexpected submitted job %s %+v", resp.Status, submitted)
+	}
+	resp, err = http.Post(server.URL+"/api/jobs", "application/json", strings.NewReader(`{"service": "subsonic", "playlists": [{"title": "Empty"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("playlist without tracks should be rejected, got %s", resp.Status)
+	}
+
+	var jobs []ServerJob
+	getJSON(t, server.URL+"/api/jobs", &jobs)
+	if len(jobs) != 2 {
+		t.Errorf("unexpected jobs %+v", jobs)
+	}
+	s.Close()
+	if len(provider.items) != 3 || provider.items[1] != "id-Second" || provider.items[2] != "id-First" {
+		t.Errorf("tracks should be added in given order, got %v", provider.items)
+	}
+	if provider.privacies["Reordered"] != "public" {
+		t.Errorf("playlist should get privacy of configuration, got %v", provider.privacies)
+	}
+}
+
+func TestServerQueueFull(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	Config = Configuration{SubsonicURL: "http://localhost", SubsonicUser: "dj", SubsonicPassword: "secret"}
+	defer func() { Config = Configuration{} }()
+
+	// the first job blocks the worker until the queue is checked
+	opened := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	s := newServer(t.TempDir())
+	s.open = func(service string, opts SessionOptions) (Session, error) {
+		once.Do(func() { close(opened) })
+		<-release
+		return nil, fmt.Errorf("service is not available")
+	}
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	job := `{"service": "subsonic", "playlists": [{"title": "Tango", "tracks": [{"Orchestra": "Francisco Canaro", "Name": "Poema"}]}]}`
+	submit := func() int {
+		resp, err := http.Post(server.URL+"/api/jobs", "application/json", strings.NewReader(job))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	submit()
+	<-opened
+	for i := 0; i < maxQueuedJobs; i++ {
+		if status := submit(); status != http.StatusAccepted {
+			t.Fatalf("job %d should be queued, got %d", i+2, status)
+		}
+	}
+	if status := submit(); status != http.StatusServiceUnavailable {
+		t.Errorf("job should be rejected when queue is full, got %d", status)
+	}
+	var jobs []ServerJob
+	getJSON(t, server.URL+"/api/jobs", &jobs)
+	if len(jobs) != maxQueuedJobs+1 {
+		t.Errorf("rejected job should not be listed, got %d jobs", len(jobs))
+	}
+	close(release)
+	s.Close()
+}
+
+func TestCallbackHandler(t *testing.T) {
+	handler := &callbackHandler{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	if resp, err := http.Get(server.URL); err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("callback without pending authorization should fail, got %v %v", resp, err)
+	}
+	var notified string
+	handler.notify = func(service, authURL string) { notified = service + " " + authURL }
+	handler.set("Spotify", "https://auth", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Query().Get("code"))
+	})
+	if service, authURL := handler.pending(); service != "Spotify" || authURL != "https://auth" || notified != "Spotify https://auth" {
+		t.Errorf("unexpected pending authorization %s %s, notified %s", service, authURL, notified)
+	}
+	resp, err := http.Get(server.URL + "?code=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body bytes.Buffer
+	body.ReadFrom(resp.Body)
+	resp.Body.Close()
+	if body.String() != "abc" {
+		t.Errorf("callback is not passed to pending authorization, got %s", body.String())
+	}
+}
+
+// helper function to get JSON response of API server
+func getJSON(t *testing.T, rurl string, data any) {
+	resp, err := http.Get(rurl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %s of %s", resp.Status, rurl)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(data); err != nil {
+		t.Fatal(err)
+	}
+}
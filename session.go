@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// Session represents authenticated session with the service, the session
+// is opened once and can be used to build many playlists
+type Session interface {
+	// Build builds playlist with given title from discography tracks, the
+	// options are applied when the playlist is created
+	Build(title string, discography *Discography, opts PlaylistOptions) (BuildResult, error)
+	// Close releases resources of the session
+	Close() error
+}
+
+// SessionOptions represents options of the service session
+type SessionOptions struct {
+	Privacies []string // privacies of playlists which will be built within the session
+	Cover     bool     // cover images of playlists will be uploaded
+	Quota     *Quota   // budget of service searches, nil means unlimited
+}
+
+// PlaylistOptions represents options of the playlist which may differ
+// between playlists built within the same session
+type PlaylistOptions struct {
+	Privacy     string // privacy of the playlist, i.e. public, private or unlisted
+	Description string // template of playlist description, default one is used if empty
+	Cover       string // cover image file, "auto" renders it, empty means no cover
+	// ReplaceCover requests cover upload of existing playlists, otherwise
+	// the cover is only set when the playlist is created
+	ReplaceCover bool
+}
+
+// helper function to open session with given service, OAuth based services
+// ask user to authorize goplaylist once per session
+func openSession(service string, opts SessionOptions) (Session, error) {
+	switch service {
+	case "spotify":
+		provider, err := spotifyProvider(opts.Privacies, opts.Cover)
+		if err != nil {
+			return nil, err
+		}
+		return newProviderSession(provider, opts.Quota), nil
+	case "youtube":
+		provider, err := youtubeProvider()
+		if err != nil {
+			return nil, err
+		}
+		return newProviderSession(provider, opts.Quota), nil
+	case "subsonic", "navidrome":
+		provider, err := subsonicProvider()
+		if err != nil {
+			return nil, err
+		}
+		return newProviderSession(provider, opts.Quota), nil
+	case "mpd":
+		client, err := mpdClient()
+		if err != nil {
+			return nil, err
+		}
+		pcache := &Cache{}
+		pcache.Init("mpd", cacheDir("mpd"))
+		return &mpdSession{client: client, cache: pcache}, nil
+	case "local":
+		if Config.MusicDir == "" {
+			return nil, fmt.Errorf("music_dir is not provided in configuration")
+		}
+		files, err := scanLibrary(Config.MusicDir)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan music directory %s: %w", Config.MusicDir, err)
+		}
+		log.Printf("Found %d audio files in %s", len(files), Config.MusicDir)
+		return &localSession{files: files}, nil
+	}
+	return nil, fmt.Errorf("unsupported service: %s", service)
+}
+
+// providerSession implements Session interface for services implementing
+// Provider interface
+type providerSession struct {
+	provider Provider
+	cache    *Cache
+	quota    *Quota
+}
+
+// helper function to create session of given provider along with its cache
+func newProviderSession(provider Provider, quota *Quota) *providerSession {
+	pcache := &Cache{}
+	pcache.Init(provider.Name(), cacheDir(provider.Name()))
+	return &providerSession{provider: provider, cache: pcache, quota: quota}
+}
+
+// Build implements Session interface
+func (s *providerSession) Build(title string, discography *Discography, opts PlaylistOptions) (BuildResult, error) {
+	cache = s.cache
+	return buildPlaylist(s.provider, title, discography, opts, s.quota)
+}
+
+// Close implements Session interface
+func (s *providerSession) Close() error {
+	return nil
+}
+
+// mpdSession implements Session interface for MPD server
+type mpdSession struct {
+	client *MPDClient
+	cache  *Cache
+}
+
+// Build implements Session interface
+func (s *mpdSession) Build(title string, discography *Discography, _ PlaylistOptions) (BuildResult, error) {
+	cache = s.cache
+	return buildMPDPlaylist(s.client, title, discography, Config.MPDLoad)
+}
+
+// Close implements Session interface
+func (s *mpdSession) Close() error {
+	return s.client.Close()
+}
+
+// localSession implements Session interface for local music library, the
+// library is scanned once per session
+type localSession struct {
+	files []LibraryFile
+}
+
+// Build implements Session interface
+func (s *localSession) Build(title string, discography *Discography, _ PlaylistOptions) (BuildResult, error) {
+	return writeLocalPlaylist(title, discography, s.files)
+}
+
+// Close implements Session interface
+func (s *localSession) Close() error {
+	return nil
+}
@@ -1,18 +1,24 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"image"
 	"log"
 	"net/http"
+	"slices"
 	"strings"
 
+	"github.com/vkuznet/goplaylist/discography"
+	"github.com/vkuznet/goplaylist/normalize"
 	spotify "github.com/zmb3/spotify/v2"
 	auth "github.com/zmb3/spotify/v2/auth"
 )
 
-// helper function to setup spotify client
-func setupSpotifyClient(title string, discography *Discography) {
+// helper function to authenticate spotify client via OAuth flow, the
+// scopes are requested for all given playlist privacies
+func spotifyProvider(privacies []string, cover bool) (*SpotifyProvider, error) {
 	ctx := context.Background()
 
 	// Create a new authenticator for Spotify
@@ -20,118 +26,147 @@ func setupSpotifyClient(title string, discography *Discography) {
 		auth.WithClientID(Config.SpotifyId),
 		auth.WithClientSecret(Config.SpotifySecret),
 		auth.WithRedirectURL(callbackUrl()),
-		auth.WithScopes(auth.ScopePlaylistModifyPublic),
+		auth.WithScopes(spotifyScopes(privacies, cover)...),
 	)
 
-	// Handle token via a callback URL and redirect
+	// Handle token via a callback URL
 	state := "random-state-string"
-	var client *spotify.Client
-	http.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+	var provider *SpotifyProvider
+	err := authorize("Spotify", auth.AuthURL(state), func(r *http.Request) error {
 		token, err := auth.Token(ctx, state, r)
 		if err != nil {
-			http.Error(w, "Couldn't get token", http.StatusForbidden)
-			log.Fatalf("Couldn't get token: %v", err)
-			return
+			return fmt.Errorf("couldn't get token: %w", err)
 		}
-
-		client = spotify.New(auth.Client(ctx, token))
-		log.Println("Spotify client successfully authenticated")
-
+		client := spotify.New(auth.Client(ctx, token))
 		user, err := client.CurrentUser(ctx)
 		if err != nil {
-			http.Error(w, "Couldn't get current user", http.StatusForbidden)
-			log.Fatalf("Couldn't get current user: %v", err)
-			return
+			return fmt.Errorf("couldn't get current user: %w", err)
 		}
+		provider = &SpotifyProvider{Client: client, UserID: user.ID}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Println("Spotify client successfully authenticated")
+	return provider, nil
+}
 
-		// obtain orchestra either from title of discography
-		orchestra := getOrchestra(title, discography)
-
-		// check if playlist already exist, if not we will create it
-		playlistID, err := getSpotifyPlaylistIDByName(client, title)
-		if err != nil {
-			log.Println("Unable to lookup playlist ID for", title)
-			playlistID = createSpotifyPlaylist(client, user.ID, title)
+// helper function to define spotify scopes we need for playlists of given
+// privacies, private playlists require additional scopes to modify and to
+// lookup them
+func spotifyScopes(privacies []string, cover bool) []string {
+	var scopes []string
+	add := func(values ...string) {
+		for _, v := range values {
+			if !slices.Contains(scopes, v) {
+				scopes = append(scopes, v)
+			}
 		}
-
-		// load cache entries for our playlist
-		tracks, err := cache.Load("spotify", title, string(playlistID))
-
-		// fetch existing tracks in our playlist
-		//         tracks, err := getSpotifyTracksForPlaylistID(client, playlistID)
-		if err != nil {
-			log.Printf("unable to find tracks for playlist '%s' (%v), error %v", title, playlistID, err)
+	}
+	for _, privacy := range privacies {
+		if privacy == "public" {
+			add(auth.ScopePlaylistModifyPublic)
+		} else {
+			add(auth.ScopePlaylistModifyPrivate, auth.ScopePlaylistReadPrivate)
 		}
+	}
+	if cover {
+		add(auth.ScopeImageUpload)
+	}
+	return scopes
+}
 
-		for idx, track := range discography.Tracks {
-			if track.Orchestra != "" {
-				orchestra = track.Orchestra
-			}
-			year := strings.Split(track.Year, "-")[0]
-			//             query := fmt.Sprintf("track:%s year:%v artist:%s", track.Name, year, artist)
-			query := fmt.Sprintf("track:%s artist:%s", track.Name, orchestra)
-			if Config.Verbose > 0 {
-				fmt.Printf("query idx: %4d track: %s\n", idx, query)
-			}
-			trk := Track{Name: track.Name, Year: year, Orchestra: orchestra, Artist: track.Artist}
-			if inList(trk, tracks) {
-				fmt.Printf("idx: %4d query: %s, already exist in playlist, skipping...\n", idx, query)
-			} else {
-				fmt.Printf("idx: %4d track: %s\n", idx, query)
-				if err := addToSpotifyPlaylist(client, playlistID, query); err == nil {
-					// add track to local cache if was successfully added to playlist
-					cache.AddTrack(title, string(playlistID), trk)
-				}
-			}
-		}
-		purl := constructSpotifyPlaylistURL(playlistID)
-		msg := fmt.Sprintf("New playlist <a href=\"%s\">%s</a> is created", purl, title)
-		log.Println(msg)
-		w.Header().Set("Content-Type", "text/html")
-		w.Write([]byte(msg))
-	})
+// SpotifyProvider implements Provider interface for Spotify service
+type SpotifyProvider struct {
+	Client *spotify.Client
+	UserID string
+}
 
-	// Start a web server to complete the auth flow
-	go func() {
-		log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", Config.CallbackPort), nil))
-	}()
+// Name implements Provider interface
+func (s *SpotifyProvider) Name() string {
+	return "spotify"
+}
+
+// FindPlaylist implements Provider interface
+func (s *SpotifyProvider) FindPlaylist(title string) (string, error) {
+	playlistID, err := getSpotifyPlaylistIDByName(s.Client, title)
+	return string(playlistID), err
+}
 
-	// Redirect user to Spotify's auth page
-	authURL := auth.AuthURL(state)
-	log.Printf("Please log in to Spotify by visiting the following page in your browser:\n%s", authURL)
+// CreatePlaylist implements Provider interface
+func (s *SpotifyProvider) CreatePlaylist(title, description, privacy string) (string, error) {
+	playlistID, err := createSpotifyPlaylist(s.Client, s.UserID, title, description, privacy)
+	return string(playlistID), err
+}
 
-	var code string
-	if _, err := fmt.Scan(&code); err != nil {
-		log.Fatalf("Unable to read authorization code %v", err)
+// Query implements Provider interface
+func (s *SpotifyProvider) Query(track Track) string {
+	//     return fmt.Sprintf("track:%s year:%v artist:%s", track.Name, track.Year, track.Orchestra)
+	if track.ISRC != "" {
+		// ISRC identifies exact recording
+		return "isrc:" + discography.NormalizeISRC(track.ISRC)
 	}
+	orchestra := normalize.DefaultResolver.Search(track.Orchestra, "spotify")
+	return fmt.Sprintf("track:%s artist:%s", track.Name, orchestra)
 }
 
-// helper function to create spotify playlist
-func createSpotifyPlaylist(client *spotify.Client, userID, title string) spotify.ID {
+// Search implements Provider interface
+func (s *SpotifyProvider) Search(query string, track Track) (Match, error) {
 	ctx := context.Background()
-	playlist, err := client.CreatePlaylistForUser(ctx, userID, title, "Playlist created for Orquesta Típica", true, false)
+	searchResults, err := s.Client.Search(ctx, query, spotify.SearchTypeTrack)
 	if err != nil {
-		log.Fatalf("Error creating Spotify playlist: %v", err)
+		return Match{}, err
+	}
+	if searchResults.Tracks == nil || len(searchResults.Tracks.Tracks) == 0 {
+		return Match{}, fmt.Errorf("%w for query: %s", errNotFound, query)
+	}
+	found := searchResults.Tracks.Tracks[0]
+	var artists []string
+	for _, artist := range found.Artists {
+		artists = append(artists, artist.Name)
+	}
+	title := found.Name
+	if len(artists) > 0 {
+		title += " - " + strings.Join(artists, ", ")
 	}
-	return playlist.ID
+	return Match{ID: string(found.ID), Title: title, Score: matchScore(track, title)}, nil
 }
 
-// helper function to add track to spotify playlist
-func addToSpotifyPlaylist(client *spotify.Client, playlistID spotify.ID, trackName string) error {
+// AddItem implements Provider interface
+func (s *SpotifyProvider) AddItem(playlistID, itemID string, track Track) error {
 	ctx := context.Background()
-	searchResults, err := client.Search(ctx, trackName, spotify.SearchTypeTrack)
-	if err != nil || len(searchResults.Tracks.Tracks) == 0 {
-		log.Printf("Error finding track: %v", err)
+	_, err := s.Client.AddTracksToPlaylist(ctx, spotify.ID(playlistID), spotify.ID(itemID))
+	return err
+}
+
+// UploadCover implements CoverUploader interface
+func (s *SpotifyProvider) UploadCover(playlistID string, img image.Image) error {
+	data, err := encodeJPEG(img, spotifyCoverLimit)
+	if err != nil {
 		return err
 	}
-	trackID := searchResults.Tracks.Tracks[0].ID
+	return s.Client.SetPlaylistImage(context.Background(), spotify.ID(playlistID), bytes.NewReader(data))
+}
+
+// PlaylistURL implements Provider interface
+func (s *SpotifyProvider) PlaylistURL(playlistID string) string {
+	return constructSpotifyPlaylistURL(spotify.ID(playlistID))
+}
 
-	_, err = client.AddTracksToPlaylist(ctx, playlistID, trackID)
+// helper function to create spotify playlist
+func createSpotifyPlaylist(client *spotify.Client, userID, title, description, privacy string) (spotify.ID, error) {
+	ctx := context.Background()
+	// spotify limits playlist description to 300 characters
+	if runes := []rune(description); len(runes) > 300 {
+		description = string(runes[:297]) + "..."
+	}
+	public := privacy == "public"
+	playlist, err := client.CreatePlaylistForUser(ctx, userID, title, description, public, false)
 	if err != nil {
-		log.Printf("Error adding track to playlist: %v", err)
-		return err
+		return "", fmt.Errorf("error creating Spotify playlist: %w", err)
 	}
-	return nil
+	return playlist.ID, nil
 }
 
 // helper function to construct spotify playlist URL
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/vkuznet/goplaylist/normalize"
+)
+
+// histogramWidth defines width of the longest bar of ASCII histograms
+const histogramWidth = 40
+
+// DiscographyStats represents statistics of discography tracks
+type DiscographyStats struct {
+	Tracks       int         `json:"tracks"`
+	Orchestras   []FactCount `json:"orchestras"`
+	Genres       []FactCount `json:"genres"`
+	Vocalists    []FactCount `json:"vocalists"`
+	Decades      []FactCount `json:"decades"`
+	Years        []FactCount `json:"years"`
+	Instrumental int         `json:"instrumental"` // number of instrumental tracks
+	Vocal        int         `json:"vocal"`        // number of tracks with vocal
+	Precision    []FactCount `json:"precision"`    // number of tracks per precision of the date
+	Uncertain    int         `json:"uncertain"`    // number of tracks with uncertain date
+}
+
+// StatsSection represents titled list of counts of the statistics
+type StatsSection struct {
+	Title  string
+	Column string
+	Counts []FactCount
+}
+
+// precisionNames defines names of date precisions used in statistics
+var precisionNames = map[DatePrecision]string{
+	DateDay:     "full date",
+	DateMonth:   "month",
+	DateYear:    "year",
+	DateDecade:  "decade",
+	DateUnknown: "unknown",
+}
+
+// helper function to sort counts by their values, e.g. years chronologically
+func sortByValue(counts []FactCount) []FactCount {
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Value < counts[j].Value })
+	return counts
+}
+
+// helper function to collect statistics of the discography
+func discographyStats(discography *Discography) DiscographyStats {
+	stats := DiscographyStats{Tracks: len(discography.Tracks)}
+	var orchestras, genres, vocalists, decades, years []string
+	precisions := make(map[DatePrecision]int)
+	for _, track := range discography.Tracks {
+		orchestra := track.Orchestra
+		if orchestra == "" {
+			orchestra = discography.Orchestra
+		}
+		orchestras = append(orchestras, normalize.Orchestra(orchestra))
+		genres = append(genres, track.Genre)
+
+		switch vocal := strings.TrimSpace(track.Vocal); {
+		case vocal == "":
+		case normalize.Key(vocal) == "instrumental":
+			stats.Instrumental++
+		default:
+			stats.Vocal++
+			vocalists = append(vocalists, strings.Split(vocal, ",")...)
+		}
+
+		precisions[track.Year.Precision()]++
+		if track.Year.Uncertain() {
+			stats.Uncertain++
+		}
+		if track.Year.Precision() != DateUnknown {
+			decades = append(decades, fmt.Sprintf("%ds", track.Year.Decade()))
+		}
+		if year := track.Year.YearString(); year != "" {
+			years = append(years, year)
+		}
+	}
+	stats.Orchestras = countValues(orchestras)
+	stats.Genres = countValues(genres)
+	stats.Vocalists = countValues(vocalists)
+	stats.Decades = sortByValue(countValues(decades))
+	stats.Years = sortByValue(countValues(years))
+	for _, p := range []DatePrecision{DateDay, DateMonth, DateYear, DateDecade, DateUnknown} {
+		if precisions[p] > 0 {
+			stats.Precision = append(stats.Precision, FactCount{Value: precisionNames[p], Count: precisions[p]})
+		}
+	}
+	return stats
+}
+
+// helper function to get sections of the statistics in order of printing
+func (s DiscographyStats) sections() []StatsSection {
+	return []StatsSection{
+		{"Orchestras", "Orchestra", s.Orchestras},
+		{"Genres", "Genre", s.Genres},
+		{"Vocalists", "Vocalist", s.Vocalists},
+		{"Decades", "Decade", s.Decades},
+		{"Years", "Year", s.Years},
+		{"Date precision", "Precision", s.Precision},
+	}
+}
+
+// helper function to get percent of tracks
+func (s DiscographyStats) percent(count int) float64 {
+	if s.Tracks == 0 {
+		return 0
+	}
+	return 100 * float64(count) / float64(s.Tracks)
+}
+
+// helper function to describe instrumental vs vocal ratio of tracks
+func (s DiscographyStats) vocalRatio() string {
+	unknown := s.Tracks - s.Instrumental - s.Vocal
+	return fmt.Sprintf("instrumental %d (%.0f%%), vocal %d (%.0f%%), unknown %d (%.0f%%)",
+		s.Instrumental, s.percent(s.Instrumental), s.Vocal, s.percent(s.Vocal), unknown, s.percent(unknown))
+}
+
+// helper function to draw histogram bar of given count
+func histogramBar(count, max int) string {
+	if max == 0 {
+		return ""
+	}
+	width := count * histogramWidth / max
+	if width == 0 && count > 0 {
+		width = 1
+	}
+	return strings.Repeat("#", width)
+}
+
+// helper function to find the largest count
+func maxCount(counts []FactCount) int {
+	var max int
+	for _, c := range counts {
+		if c.Count > max {
+			max = c.Count
+		}
+	}
+	return max
+}
+
+// helper function to write statistics in given format: text, json or markdown
+func writeStats(w io.Writer, stats DiscographyStats, format string, histogram bool) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case "markdown", "md":
+		fmt.Fprintf(w, "## Discography statistics\n\n")
+		fmt.Fprintf(w, "- tracks: %d\n- vocal: %s\n- uncertain dates: %d\n", stats.Tracks, stats.vocalRatio(), stats.Uncertain)
+		for _, section := range stats.sections() {
+			fmt.Fprintf(w, "\n### %s\n\n", section.Title)
+			if histogram {
+				fmt.Fprintf(w, "| %s | Tracks | %% | Histogram |\n|---|---:|---:|---|\n", section.Column)
+			} else {
+				fmt.Fprintf(w, "| %s | Tracks | %% |\n|---|---:|---:|\n", section.Column)
+			}
+			max := maxCount(section.Counts)
+			for _, c := range section.Counts {
+				value := strings.ReplaceAll(c.Value, "|", "\\|")
+				if histogram {
+					fmt.Fprintf(w, "| %s | %d | %.1f | `%s` |\n", value, c.Count, stats.percent(c.Count), histogramBar(c.Count, max))
+				} else {
+					fmt.Fprintf(w, "| %s | %d | %.1f |\n", value, c.Count, stats.percent(c.Count))
+				}
+			}
+		}
+		return nil
+	case "", "text":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "Tracks: %d\n", stats.Tracks)
+		fmt.Fprintf(tw, "Vocal: %s\n", stats.vocalRatio())
+		fmt.Fprintf(tw, "Uncertain dates: %d\n", stats.Uncertain)
+		for _, section := range stats.sections() {
+			fmt.Fprintf(tw, "\n%s:\n", section.Title)
+			max := maxCount(section.Counts)
+			for _, c := range section.Counts {
+				fmt.Fprintf(tw, "  %s\t%d\t%5.1f%%", c.Value, c.Count, stats.percent(c.Count))
+				if histogram {
+					fmt.Fprintf(tw, "\t%s", histogramBar(c.Count, max))
+				}
+				fmt.Fprintln(tw)
+			}
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unsupported stats format '%s', should be text, json or markdown", format)
+}
+
+// statsCommand reports statistics of discography tracks
+func statsCommand(args []string) error {
+	var opts Options
+	var format string
+	var histogram bool
+	fs := newFlagSet("stats", "Report statistics of discography tracks: counts by orchestra, genre, vocalist, decade\nand year, instrumental vs vocal ratio and precision of recording dates.")
+	opts.discographyFlags(fs)
+	fs.StringVar(&format, "format", "text", "output format: text, json or markdown")
+	fs.BoolVar(&histogram, "histogram", false, "draw ASCII histograms of counts")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	discography, err := opts.readDiscography()
+	if err != nil {
+		return err
+	}
+	return writeStats(os.Stdout, discographyStats(discography), strings.ToLower(format), histogram)
+}
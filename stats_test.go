@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDiscographyStats(t *testing.T) {
+	discography := &Discography{
+		Orchestra: "Carlos Di Sarli",
+		Tracks: []Track{
+			{Name: "Bahía Blanca", Year: "1957-11-13", Genre: "Tango", Vocal: "Instrumental"},
+			{Name: "Corazón", Year: "1939", Genre: "tango", Vocal: "Ignacio Murillo"},
+			{Name: "La capilla blanca", Year: "194?", Genre: "Tango", Vocal: "Roberto Rufino, Jorge Durán"},
+			{Name: "Rosamel", Orchestra: "Juan D'Arienzo", Genre: "Vals"},
+		},
+	}
+	stats := discographyStats(discography)
+	if stats.Tracks != 4 || stats.Instrumental != 1 || stats.Vocal != 2 || stats.Uncertain != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	expect := map[string][]FactCount{
+		"orchestras": {{"Carlos Di Sarli", 3}, {"Juan D'Arienzo", 1}},
+		"genres":     {{"Tango", 3}, {"Vals", 1}},
+		"decades":    {{"1930s", 1}, {"1940s", 1}, {"1950s", 1}},
+		"years":      {{"1939", 1}, {"1957", 1}},
+		"precision":  {{"full date", 1}, {"year", 1}, {"decade", 1}, {"unknown", 1}},
+	}
+	got := map[string][]FactCount{
+		"orchestras": stats.Orchestras,
+		"genres":     stats.Genres,
+		"decades":    stats.Decades,
+		"years":      stats.Years,
+		"precision":  stats.Precision,
+	}
+	for key, counts := range expect {
+		if len(got[key]) != len(counts) {
+			t.Errorf("%s: expected %v, got %v", key, counts, got[key])
+			continue
+		}
+		for i := range counts {
+			if got[key][i] != counts[i] {
+				t.Errorf("%s: expected %v, got %v", key, counts, got[key])
+				break
+			}
+		}
+	}
+	if len(stats.Vocalists) != 3 {
+		t.Errorf("expected 3 vocalists, got %v", stats.Vocalists)
+	}
+
+	var buf bytes.Buffer
+	if err := writeStats(&buf, stats, "json", false); err != nil {
+		t.Fatal(err)
+	}
+	var decoded DiscographyStats
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || decoded.Tracks != 4 {
+		t.Errorf("unable to decode json stats: %v, %s", err, buf.String())
+	}
+
+	buf.Reset()
+	if err := writeStats(&buf, stats, "markdown", true); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "| Carlos Di Sarli | 3 | 75.0 | `"+strings.Repeat("#", histogramWidth)+"` |") {
+		t.Errorf("unexpected markdown stats:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeStats(&buf, stats, "text", false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Vocal: instrumental 1 (25%), vocal 2 (50%), unknown 1 (25%)") {
+		t.Errorf("unexpected text stats:\n%s", buf.String())
+	}
+
+	if err := writeStats(&buf, stats, "xml", false); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// subsonicAPIVersion defines version of Subsonic REST API we speak,
+// token based authentication requires at least 1.13.0
+const subsonicAPIVersion = "1.16.1"
+
+// subsonicClientName defines name of our client reported to the server
+const subsonicClientName = "goplaylist"
+
+// helper function to setup subsonic client, Subsonic API is provided by
+// self-hosted music servers like Navidrome, Airsonic, Gonic or Jellyfin
+// (via its Subsonic plugin) and it does not require OAuth flow
+func subsonicProvider() (*SubsonicProvider, error) {
+	provider := &SubsonicProvider{
+		URL:      Config.SubsonicURL,
+		User:     Config.SubsonicUser,
+		Password: Config.SubsonicPassword,
+	}
+	if err := provider.Ping(); err != nil {
+		return nil, fmt.Errorf("unable to connect to subsonic server %s: %w", Config.SubsonicURL, err)
+	}
+	log.Println("Subsonic client successfully authenticated")
+	return provider, nil
+}
+
+// SubsonicProvider implements Provider interface for Subsonic API compatible servers
+type SubsonicProvider struct {
+	URL      string       // base URL of the server, e.g. http://localhost:4533
+	User     string       // user name
+	Password string       // user password, it is never sent over the wire
+	Client   *http.Client // HTTP client to use, if nil a default one is created
+}
+
+// subsonicSong represents song entry of subsonic response
+type subsonicSong struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Artist string `json:"artist"`
+	Album  string `json:"album"`
+	Year   int    `json:"year"`
+}
+
+// subsonicPlaylist represents playlist entry of subsonic response
+type subsonicPlaylist struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	SongCount int    `json:"songCount"`
+}
+
+// subsonicError represents error entry of subsonic response
+type subsonicError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// SubsonicResponse represents response of Subsonic REST API
+type SubsonicResponse struct {
+	Status        string         `json:"status"`
+	Version       string         `json:"version"`
+	Error         *subsonicError `json:"error,omitempty"`
+	SearchResult3 struct {
+		Song []subsonicSong `json:"song"`
+	} `json:"searchResult3"`
+	Playlists struct {
+		Playlist []subsonicPlaylist `json:"playlist"`
+	} `json:"playlists"`
+	Playlist subsonicPlaylist `json:"playlist"`
+}
+
+// helper function to generate random salt for subsonic token authentication
+func subsonicSalt() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// helper function to compute subsonic authentication token, i.e. md5(password + salt)
+func subsonicToken(password, salt string) string {
+	sum := md5.Sum([]byte(password + salt))
+	return hex.EncodeToString(sum[:])
+}
+
+// helper function to call subsonic API method with given parameters
+func (s *SubsonicProvider) call(method string, params url.Values) (*SubsonicResponse, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	salt := subsonicSalt()
+	params.Set("u", s.User)
+	params.Set("t", subsonicToken(s.Password, salt))
+	params.Set("s", salt)
+	params.Set("v", subsonicAPIVersion)
+	params.Set("c", subsonicClientName)
+	params.Set("f", "json")
+	rurl := fmt.Sprintf("%s/rest/%s?%s", strings.TrimSuffix(s.URL, "/"), method, params.Encode())
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Get(rurl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("subsonic %s request failed with status %s", method, resp.Status)
+	}
+
+	var rec struct {
+		Response SubsonicResponse `json:"subsonic-response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rec); err != nil {
+		return nil, fmt.Errorf("unable to decode subsonic %s response: %w", method, err)
+	}
+	if rec.Response.Status != "ok" {
+		if rec.Response.Error != nil {
+			return nil, fmt.Errorf("subsonic %s error %d: %s",
+				method, rec.Response.Error.Code, rec.Response.Error.Message)
+		}
+		return nil, fmt.Errorf("subsonic %s failed with status '%s'", method, rec.Response.Status)
+	}
+	return &rec.Response, nil
+}
+
+// Ping checks connectivity and credentials with subsonic server
+func (s *SubsonicProvider) Ping() error {
+	_, err := s.call("ping", nil)
+	return err
+}
+
+// Name implements Provider interface
+func (s *SubsonicProvider) Name() string {
+	return "subsonic"
+}
+
+// FindPlaylist implements Provider interface
+func (s *SubsonicProvider) FindPlaylist(title string) (string, error) {
+	resp, err := s.call("getPlaylists", nil)
+	if err != nil {
+		return "", fmt.Errorf("error fetching user's playlists: %w", err)
+	}
+	for _, playlist := range resp.Playlists.Playlist {
+		if playlist.Name == title {
+			return playlist.ID, nil
+		}
+	}
+	return "", fmt.Errorf("no playlist found with name: %s", title)
+}
+
+// CreatePlaylist implements Provider interface
+func (s *SubsonicProvider) CreatePlaylist(title, description, privacy string) (string, error) {
+	params := url.Values{}
+	params.Set("name", title)
+	resp, err := s.call("createPlaylist", params)
+	if err != nil {
+		return "", err
+	}
+	playlistID := resp.Playlist.ID
+	if playlistID == "" {
+		// servers implementing API prior 1.14.0 return empty response, and we
+		// should lookup newly created playlist by its name
+		playlistID, err = s.FindPlaylist(title)
+		if err != nil {
+			return "", err
+		}
+	}
+	// createPlaylist does not accept description and visibility of the
+	// playlist, therefore we set them separately
+	params = url.Values{}
+	params.Set("playlistId", playlistID)
+	params.Set("comment", description)
+	params.Set("public", fmt.Sprintf("%v", privacy == "public"))
+	if _, err := s.call("updatePlaylist", params); err != nil {
+		return "", err
+	}
+	return playlistID, nil
+}
+
+// Query implements Provider interface
+func (s *SubsonicProvider) Query(track Track) string {
+	return fmt.Sprintf("%s %s", track.Name, track.Orchestra)
+}
+
+// Search implements Provider interface, it uses search3 API and picks the
+// song which best matches track name, orchestra and year. If nothing is found
+// for given query we fall back to search by track name alone since local
+// libraries often tag orchestras differently from our discographies.
+func (s *SubsonicProvider) Search(query string, track Track) (Match, error) {
+	songs, err := s.search(query)
+	if err != nil {
+		return Match{}, err
+	}
+	if len(songs) == 0 && query != track.Name {
+		songs, err = s.search(track.Name)
+		if err != nil {
+			return Match{}, err
+		}
+	}
+	if len(songs) == 0 {
+		return Match{}, fmt.Errorf("%w for query: %s", errNotFound, query)
+	}
+	best, bestScore := songs[0], -1
+	for _, song := range songs {
+		score := subsonicScore(song, track)
+		if score > bestScore {
+			best, bestScore = song, score
+		}
+	}
+	if bestScore == 0 {
+		return Match{}, fmt.Errorf("%w, songs do not contain '%s' for query: %s", errNotFound, track.Name, query)
+	}
+	title := best.Title + " - " + best.Artist
+	return Match{ID: best.ID, Title: title, Score: matchScore(track, title)}, nil
+}
+
+// helper function to perform search3 API call
+func (s *SubsonicProvider) search(query string) ([]subsonicSong, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("songCount", "20")
+	params.Set("artistCount", "0")
+	params.Set("albumCount", "0")
+	resp, err := s.call("search3", params)
+	if err != nil {
+		return nil, err
+	}
+	return resp.SearchResult3.Song, nil
+}
+
+// helper function to score subsonic song against our track, see songScore
+func subsonicScore(song subsonicSong, track Track) int {
+	var year string
+	if song.Year > 0 {
+		year = strconv.Itoa(song.Year)
+	}
+	return songScore(track, song.Title, year, song.Artist)
+}
+
+// AddItem implements Provider interface
+func (s *SubsonicProvider) AddItem(playlistID, songID string, track Track) error {
+	params := url.Values{}
+	params.Set("playlistId", playlistID)
+	params.Set("songIdToAdd", songID)
+	_, err := s.call("updatePlaylist", params)
+	return err
+}
+
+// PlaylistURL implements Provider interface, it points to Navidrome web UI
+// which is the most common Subsonic API server
+func (s *SubsonicProvider) PlaylistURL(playlistID string) string {
+	return fmt.Sprintf("%s/app/#/playlist/%s/show", strings.TrimSuffix(s.URL, "/"), playlistID)
+}
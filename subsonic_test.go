@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeSubsonic represents stand-in subsonic server used in tests
+type fakeSubsonic struct {
+	password  string
+	songs     []subsonicSong
+	playlists map[string][]string // playlist name -> song ids
+}
+
+// helper function to write subsonic response
+func (f *fakeSubsonic) reply(w http.ResponseWriter, resp map[string]any) {
+	resp["status"] = "ok"
+	resp["version"] = subsonicAPIVersion
+	json.NewEncoder(w).Encode(map[string]any{"subsonic-response": resp})
+}
+
+// ServeHTTP implements http.Handler interface
+func (f *fakeSubsonic) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("t") != subsonicToken(f.password, q.Get("s")) || q.Get("p") != "" {
+		json.NewEncoder(w).Encode(map[string]any{"subsonic-response": map[string]any{
+			"status": "failed",
+			"error":  map[string]any{"code": 40, "message": "Wrong username or password"},
+		}})
+		return
+	}
+	switch strings.TrimPrefix(r.URL.Path, "/rest/") {
+	case "ping":
+		f.reply(w, map[string]any{})
+	case "getPlaylists":
+		var playlists []subsonicPlaylist
+		for name, ids := range f.playlists {
+			playlists = append(playlists, subsonicPlaylist{ID: name, Name: name, SongCount: len(ids)})
+		}
+		f.reply(w, map[string]any{"playlists": map[string]any{"playlist": playlists}})
+	case "createPlaylist":
+		name := q.Get("name")
+		f.playlists[name] = []string{}
+		f.reply(w, map[string]any{"playlist": subsonicPlaylist{ID: name, Name: name}})
+	case "updatePlaylist":
+		pid := q.Get("playlistId")
+		f.playlists[pid] = append(f.playlists[pid], q["songIdToAdd"]...)
+		f.reply(w, map[string]any{})
+	case "search3":
+		var songs []subsonicSong
+		for _, song := range f.songs {
+			text := strings.ToLower(song.Title + " " + song.Artist)
+			match := true
+			for _, word := range strings.Fields(strings.ToLower(q.Get("query"))) {
+				if !strings.Contains(text, word) {
+					match = false
+				}
+			}
+			if match {
+				songs = append(songs, song)
+			}
+		}
+		f.reply(w, map[string]any{"searchResult3": map[string]any{"song": songs}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestSubsonicProvider(t *testing.T) {
+	fake := &fakeSubsonic{
+		password: "secret",
+		songs: []subsonicSong{
+			{ID: "1", Title: "La cumparsita", Artist: "Anibal Troilo", Year: 1943},
+			{ID: "2", Title: "La cumparsita", Artist: "Anibal Troilo", Year: 1951},
+			{ID: "3", Title: "Sin rumbo fijo", Artist: "OTV", Year: 1938},
+		},
+		playlists: make(map[string][]string),
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	cache = &Cache{}
+	cache.Init("subsonic", t.TempDir())
+
+	provider := &SubsonicProvider{URL: server.URL, User: "dj", Password: "secret"}
+	if err := provider.Ping(); err != nil {
+		t.Fatalf("unable to ping fake server: %v", err)
+	}
+	bad := &SubsonicProvider{URL: server.URL, User: "dj", Password: "wrong"}
+	if err := bad.Ping(); err == nil {
+		t.Fatal("expected authentication error for wrong password")
+	}
+
+	discography := &Discography{
+		Tracks: []Track{
+			{Name: "La cumparsita", Year: "1951", Orchestra: "Anibal Troilo"},
+			{Name: "Sin rumbo fijo", Year: "1938-04-18", Orchestra: "Orquesta Tipica Victor"},
+			{Name: "Unknown track", Year: "1930", Orchestra: "Unknown"},
+		},
+	}
+	title := "Milonga"
+	result, err := buildPlaylist(provider, title, discography, PlaylistOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 2 || result.NotFound != 1 {
+		t.Errorf("wrong build result %+v", result)
+	}
+	var outcomes []string
+	for _, tr := range result.Tracks {
+		outcomes = append(outcomes, fmt.Sprintf("%s:%s:%d", tr.Outcome, tr.ServiceID, tr.Score))
+	}
+	if strings.Join(outcomes, ",") != "added:2:100,added:3:70,not-found::0" {
+		t.Errorf("wrong outcome of tracks %v", outcomes)
+	}
+	if purl := result.URL; purl != fmt.Sprintf("%s/app/#/playlist/%s/show", server.URL, title) {
+		t.Errorf("unexpected playlist URL %s", purl)
+	}
+	expect := []string{"2", "3"}
+	if strings.Join(fake.playlists[title], ",") != strings.Join(expect, ",") {
+		t.Errorf("wrong playlist content %v, expected %v", fake.playlists[title], expect)
+	}
+
+	// second run should use existing playlist and local cache
+	result, err = buildPlaylist(provider, title, discography, PlaylistOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 0 || result.Existing != 2 {
+		t.Errorf("wrong build result on second run %+v", result)
+	}
+	if len(fake.playlists) != 1 || len(fake.playlists[title]) != len(expect) {
+		t.Errorf("playlist was modified on second run: %v", fake.playlists)
+	}
+
+	// matches which score below min_score are not added
+	Config.MinScore = 80
+	defer func() { Config.MinScore = 0 }()
+	result, err = buildPlaylist(provider, "Tango", discography, PlaylistOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Added != 1 || result.LowConfidence != 1 || result.Tracks[1].Outcome != OutcomeLowConfidence {
+		t.Errorf("wrong build result with min score %+v", result)
+	}
+	if len(fake.playlists["Tango"]) != 1 {
+		t.Errorf("low confidence match should not be added: %v", fake.playlists["Tango"])
+	}
+}
+
+func TestSubsonicScore(t *testing.T) {
+	track := Track{Name: "Pájaro azul", Year: "1941-03-05", Orchestra: "Orquesta Típica Víctor"}
+	cases := []struct {
+		song  subsonicSong
+		score int
+	}{
+		// accents, case and punctuation of titles are ignored
+		{subsonicSong{Title: "Pajaro Azul", Artist: "Orquesta Tipica Victor", Year: 1941}, 4},
+		{subsonicSong{Title: "Pájaro azul (vals)", Artist: "Orquesta Tipica Victor", Year: 1941}, 3},
+		// orchestra alias matches its canonical name
+		{subsonicSong{Title: "pajaro azul", Artist: "OTV", Year: 1941}, 4},
+		{subsonicSong{Title: "Pájaro azul", Artist: "Orq. Típica Víctor y su Orquesta Típica"}, 3},
+		{subsonicSong{Title: "Pájaro azul", Artist: "Francisco Canaro", Year: 1941}, 3},
+		// title should contain all words of track name
+		{subsonicSong{Title: "Pájaro", Artist: "OTV", Year: 1941}, 0},
+		{subsonicSong{Title: "Pájaros azules", Artist: "OTV", Year: 1941}, 0},
+	}
+	for _, c := range cases {
+		if score := subsonicScore(c.song, track); score != c.score {
+			t.Errorf("wrong score %d of %+v, expected %d", score, c.song, c.score)
+		}
+	}
+}
@@ -2,4 +2,15 @@ module github.com/vkuznet/goplaylist/trackRecordings
 
 go 1.23.4
 
+require (
+	github.com/vkuznet/goplaylist/discography v0.0.0
+	github.com/vkuznet/goplaylist/normalize v0.0.0
+)
+
 require golang.org/x/text v0.22.0 // indirect
+
+// shared packages are taken from sibling modules of goplaylist
+replace (
+	github.com/vkuznet/goplaylist/discography => ../discography
+	github.com/vkuznet/goplaylist/normalize => ../normalize
+)
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+
+	"github.com/vkuznet/goplaylist/normalize"
 )
 
 func PrintStats(tracks []Track) {
@@ -11,7 +13,7 @@ func PrintStats(tracks []Track) {
 
 	tmap := make(map[string]string) // name -> genre
 	for _, track := range tracks {
-		tName := capitalize(ConvertUTFToASCII(track.Name))
+		tName := capitalize(normalize.Fold(track.Name))
 		if track.Year == "" {
 			track.Year = "19xx"
 		}
@@ -21,7 +23,7 @@ func PrintStats(tracks []Track) {
 		if _, ok := stats[tName][orchestra(track.Orchestra)]; !ok {
 			stats[tName][orchestra(track.Orchestra)] = make(map[string]struct{})
 		}
-		stats[tName][orchestra(track.Orchestra)][track.Year] = struct{}{}
+		stats[tName][orchestra(track.Orchestra)][string(track.Year)] = struct{}{}
 		tmap[tName] = capitalize(track.Genre)
 	}
 
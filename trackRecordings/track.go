@@ -3,19 +3,13 @@ package main
 import (
 	"encoding/xml"
 	"fmt"
-)
 
-type Track struct {
-	Name      string `xml:"name,attr"`
-	Year      string `xml:"year,attr"`
-	Orchestra string `xml:"orchestra,attr"`
-	Genre     string `xml:"genre,attr"`
-}
+	"github.com/vkuznet/goplaylist/discography"
+	"github.com/vkuznet/goplaylist/normalize"
+)
 
-type Discography struct {
-	Orchestra string  `xml:"orchestra,attr"`
-	Tracks    []Track `xml:"track"`
-}
+// Track represents discography track shared with goplaylist
+type Track = discography.Track
 
 type Tracks struct {
 	XMLName xml.Name `xml:"tracks"`
@@ -28,7 +22,7 @@ func findSimilarTracks(tracks []Track) []Track {
 
 	// Group tracks by name
 	for _, track := range tracks {
-		tName := capitalize(ConvertUTFToASCII(track.Name))
+		tName := capitalize(normalize.Fold(track.Name))
 		seen[tName] = append(seen[tName], track)
 	}
 
@@ -48,7 +42,7 @@ func findSimilarTracks(tracks []Track) []Track {
 	// Remove duplicates from result
 	unique := make(map[string]Track)
 	for _, track := range result {
-		tName := capitalize(ConvertUTFToASCII(track.Name))
+		tName := capitalize(normalize.Fold(track.Name))
 		//         key := fmt.Sprintf("%s|%s|%s", tName, track.Year, track.Orchestra)
 		key := fmt.Sprintf("%s|%s|%s|%s", tName, track.Year, orchestra(track.Orchestra), capitalize(track.Genre))
 		unique[key] = track
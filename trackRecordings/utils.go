@@ -3,12 +3,12 @@ package main
 import (
 	"encoding/xml"
 	"io/ioutil"
-	"os"
 	"sort"
 	"strings"
 	"unicode"
 
-	"golang.org/x/text/unicode/norm"
+	"github.com/vkuznet/goplaylist/discography"
+	"github.com/vkuznet/goplaylist/normalize"
 )
 
 // helper function to write XML file
@@ -35,31 +35,19 @@ func writeXMLFile(outputFile string, tracks []Track) error {
 
 // helper function to parse intput XML file
 func parseXMLFile(filename string) ([]Track, error) {
-	file, err := os.Open(filename)
+	disc, err := discography.ReadXMLFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	byteValue, err := ioutil.ReadAll(file)
-	if err != nil {
-		return nil, err
-	}
-
-	var discography Discography
-	err = xml.Unmarshal(byteValue, &discography)
-	if err != nil {
-		return nil, err
-	}
-
-	for i := range discography.Tracks {
+	for i := range disc.Tracks {
 		// Patch orchestra if missing
-		if discography.Tracks[i].Orchestra == "" {
-			discography.Tracks[i].Orchestra = orchestra(discography.Orchestra)
+		if disc.Tracks[i].Orchestra == "" {
+			disc.Tracks[i].Orchestra = orchestra(disc.Orchestra)
 		}
 	}
 
-	return discography.Tracks, nil
+	return disc.Tracks, nil
 }
 
 // helper function to capitalize word
@@ -71,29 +59,8 @@ func capitalize(word string) string {
 	return string(unicode.ToUpper(rune(word[0]))) + strings.ToLower(word[1:])
 }
 
-// helper function convertUTFToASCII removes diacritics
-func convertUTFToASCII(input string) string {
-	// Normalize the string to decompose characters (NFD form)
-	t := norm.NFD.String(input)
-
-	// Filter out non-spacing marks (diacritics)
-	var result strings.Builder
-	for _, r := range t {
-		if unicode.IsMark(r) {
-			continue // Skip diacritical marks
-		}
-		result.WriteRune(r)
-	}
-	return result.String()
-}
-
-// ConvertUTFToASCII removes diacritics and converts the string to lowercase ASCII
-func ConvertUTFToASCII(input string) string {
-	// Convert to lowercase
-	return strings.ToLower(convertUTFToASCII(input))
-}
-
-// helper function to normalize track orchestra
+// helper function to normalize track orchestra, i.e. canonical orchestra
+// name without diacritics
 func orchestra(o string) string {
-	return convertUTFToASCII(strings.Trim(strings.Split(o, "(")[0], " "))
+	return normalize.RemoveDiacritics(normalize.Orchestra(o))
 }
@@ -3,11 +3,16 @@ module updateiTunes
 go 1.24.4
 
 require (
-	github.com/dhowden/tag v0.0.0-20240417053706-3d75831295e8
-	golang.org/x/text v0.26.0
+	github.com/bogem/id3v2 v1.2.0
+	github.com/lithammer/fuzzysearch v1.1.8
+	github.com/vkuznet/goplaylist/discography v0.0.0
+	github.com/vkuznet/goplaylist/normalize v0.0.0
 )
 
-require (
-	github.com/bogem/id3v2 v1.2.0 // indirect
-	github.com/lithammer/fuzzysearch v1.1.8 // indirect
+require golang.org/x/text v0.26.0 // indirect
+
+// shared packages are taken from sibling modules of goplaylist
+replace (
+	github.com/vkuznet/goplaylist/discography => ../discography
+	github.com/vkuznet/goplaylist/normalize => ../normalize
 )
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/lithammer/fuzzysearch/fuzzy"
+	"github.com/vkuznet/goplaylist/normalize"
 )
 
 type MatchMode string
@@ -27,11 +28,13 @@ func MatchTracks(files []string, discography []Track, mode MatchMode, verbose in
 		base := filepath.Base(file)
 		name := strings.TrimSuffix(base, filepath.Ext(base))
 		name = strings.ToLower(name)
+		nameKey := normalize.Key(name)
 
 		var bestMatch *Track
 		for _, track := range discography {
 			matchKey := strings.ToLower(track.Name)
-			if mode == Strict && name == matchKey {
+			// strict mode ignores case, diacritics and punctuation
+			if mode == Strict && nameKey == normalize.Key(matchKey) {
 				if verbose > 1 {
 					fmt.Printf("strict mode: file '%s' match key '%s' track %+v\n", name, matchKey, track)
 				}
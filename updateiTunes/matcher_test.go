@@ -14,6 +14,14 @@ func TestStrictMatch(t *testing.T) {
 	if len(results) != 1 {
 		t.Errorf("expected 1 match, got %d", len(results))
 	}
+
+	// file names without diacritics and punctuation match as well
+	xmlTracks = []Track{{Name: "Añoranzas", Year: "1937"}, {Name: "¿Dónde estás corazón?", Year: "1941"}}
+	files = []string{"Anoranzas.mp3", "Donde estas corazon.mp3"}
+	results = MatchTracks(files, xmlTracks, Strict, 0)
+	if len(results) != 2 {
+		t.Errorf("expected 2 normalized matches, got %d", len(results))
+	}
 }
 
 func TestFuzzyMatch(t *testing.T) {
@@ -2,27 +2,15 @@
 package main
 
 import (
-	"encoding/xml"
 	"fmt"
 	"os"
 	"path/filepath"
-)
 
-// Track represents a single track entry in the XML file.
-type Track struct {
-	Name     string `xml:"name,attr"`
-	Vocal    string `xml:"vocal,attr"`
-	Year     string `xml:"year,attr"`
-	Genre    string `xml:"genre,attr"`
-	Composer string `xml:"composer,attr"`
-	Author   string `xml:"author,attr"`
-	Label    string `xml:"label,attr"`
-}
+	"github.com/vkuznet/goplaylist/discography"
+)
 
-// Discography is the root element of the XML file containing multiple tracks.
-type Discography struct {
-	Tracks []Track `xml:"track"`
-}
+// Track represents a single track entry in the XML file, it is shared with goplaylist.
+type Track = discography.Track
 
 // ParseXML accepts a glob pattern (e.g., "Francisco Canaro*.xml"), reads all matching XML files,
 // and returns a flattened slice of all tracks.
@@ -38,19 +26,13 @@ func ParseXML(pattern string) ([]Track, error) {
 	var allTracks []Track
 
 	for _, file := range matches {
-		data, err := os.ReadFile(file)
+		disc, err := discography.ReadXMLFile(file)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: could not read file %s: %v\n", file, err)
-			continue
-		}
-
-		var discography Discography
-		if err := xml.Unmarshal(data, &discography); err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: could not parse XML in file %s: %v\n", file, err)
 			continue
 		}
 
-		allTracks = append(allTracks, discography.Tracks...)
+		allTracks = append(allTracks, disc.Tracks...)
 	}
 
 	return allTracks, nil
@@ -40,8 +40,8 @@ func writeTag(tag *id3v2.Tag, orchestra, filePath string, track *Track, fixTitle
 	tag.SetArtist(orchestra)
 	tag.SetGenre(track.Genre)
 
-	if len(track.Year) >= 4 {
-		tag.SetYear(track.Year[:4])
+	if year := track.Year.YearString(); year != "" {
+		tag.SetYear(year)
 	}
 
 	tag.AddTextFrame("TPE2", tag.DefaultEncoding(), track.Vocal)    // Album Artist
@@ -2,10 +2,14 @@ package main
 
 import (
 	"fmt"
+	"log"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
-	"unicode"
+
+	"github.com/vkuznet/goplaylist/normalize"
 )
 
 // createClientWithToken creates an HTTP client that includes an authorization token in each request
@@ -45,37 +49,112 @@ func getOrchestra(title string, discography *Discography) string {
 	if discography != nil && discography.Orchestra != "" {
 		orchestra = discography.Orchestra
 	}
-	words := strings.Fields(orchestra) // Split title into words
-	var result []string
+	// keep only words, i.e. drop years, dashes and other punctuation
+	return strings.Join(normalize.Words(orchestra), " ")
+}
 
-	for _, word := range words {
-		if isAlphabetical(word) {
-			result = append(result, word)
-		}
+// helper function to construct callback URL
+func callbackUrl() string {
+	url := fmt.Sprintf("http://localhost:%d/callback", Config.CallbackPort)
+	return url
+}
+
+// callbackHandler passes OAuth callback requests to pending authorization,
+// it is used when goplaylist serves its HTTP API on callback port
+type callbackHandler struct {
+	mu      sync.Mutex
+	service string           // service which waits for authorization
+	authURL string           // authorization URL of the service
+	handler http.HandlerFunc // handler of callback request
+	notify  func(service, authURL string)
+}
+
+// oauthCallbacks receives OAuth callbacks when goplaylist runs in server
+// mode, otherwise authorize starts its own callback server
+var oauthCallbacks *callbackHandler
+
+// helper function to set pending authorization, nil handler clears it
+func (c *callbackHandler) set(service, authURL string, handler http.HandlerFunc) {
+	c.mu.Lock()
+	c.service, c.authURL, c.handler = service, authURL, handler
+	notify := c.notify
+	c.mu.Unlock()
+	if handler != nil && notify != nil {
+		notify(service, authURL)
 	}
-	return strings.Join(result, " ")
 }
 
-// Helper function to check if a word contains only alphabetical characters
-func isAlphabetical(word string) bool {
-	for _, char := range word {
-		if !unicode.IsLetter(char) {
-			return false
-		}
+// helper function to get service and URL of pending authorization
+func (c *callbackHandler) pending() (string, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.service, c.authURL
+}
+
+// ServeHTTP passes callback request to pending authorization
+func (c *callbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	handler := c.handler
+	c.mu.Unlock()
+	if handler == nil {
+		http.Error(w, "no pending authorization", http.StatusNotFound)
+		return
 	}
-	return true
+	handler(w, r)
 }
 
-// helper function to construct callback URL
-func callbackUrl() string {
-	url := fmt.Sprintf("http://localhost:%d/callback", Config.CallbackPort)
-	return url
+// authTimeout defines how long we wait for user to complete authorization
+var authTimeout = 5 * time.Minute
+
+// helper function to run OAuth authorization flow, it starts local web
+// server to receive callback request (unless goplaylist API server receives
+// them), asks user to visit authorization URL and waits until callback
+// request is processed by given handler or authorization times out
+func authorize(service, authURL string, handler func(r *http.Request) error) error {
+	done := make(chan error, 1)
+	callback := func(w http.ResponseWriter, r *http.Request) {
+		err := handler(r)
+		if err != nil {
+			http.Error(w, "Authorization failed", http.StatusForbidden)
+		} else {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprintf(w, "%s client is authorized, you may close this window", service)
+		}
+		select {
+		case done <- err:
+		default:
+		}
+	}
+	if oauthCallbacks != nil {
+		oauthCallbacks.set(service, authURL, callback)
+		defer oauthCallbacks.set("", "", nil)
+	} else {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/callback", callback)
+		listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", Config.CallbackPort))
+		if err != nil {
+			return fmt.Errorf("unable to start callback server: %w", err)
+		}
+		server := &http.Server{Handler: mux}
+		go server.Serve(listener)
+		defer server.Close()
+	}
+
+	log.Printf("Please log in to %s by visiting the following page in your browser:\n%s", service, authURL)
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(authTimeout):
+		return fmt.Errorf("%s authorization timed out after %v", service, authTimeout)
+	}
 }
 
-// helper function to check track object in tracklist
+// helper function to check track object in tracklist, tracks are compared
+// by their normalized keys
 func inList(track Track, trackList []Track) bool {
+	key := track.Key()
 	for _, t := range trackList {
-		if t.String() == track.String() {
+		if t.Key() == key {
 			return true
 		}
 	}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestOrchestra
@@ -18,7 +21,13 @@ func TestOrchestra(t *testing.T) {
 	if orchestra != "OTV" {
 		t.Errorf("Fail to parse input '%s': expect='%s' received='%s' ", ainput, "OTV", orchestra)
 	}
-
+	// words with apostrophes are kept
+	ainput = "Juan D'Arienzo - Vals (1935-1940)"
+	expect = "Juan D'Arienzo Vals"
+	orchestra = getOrchestra(ainput, nil)
+	if orchestra != expect {
+		t.Errorf("Fail to parse input '%s': expect='%s' received='%s' ", ainput, expect, orchestra)
+	}
 }
 
 func TestInUtils(t *testing.T) {
@@ -32,4 +41,52 @@ func TestInUtils(t *testing.T) {
 			t.Errorf("unable to find item '%s' in a list %s", trk.String(), trackList)
 		}
 	}
+	// tracks are compared after normalization
+	trk := Track{Name: "la cumparsita", Year: "1938", Orchestra: "Orq. Típica Víctor"}
+	cached := []Track{{Name: "La Cumparsita", Year: "1938", Orchestra: "Orquesta Tipica Victor (dir. Adolfo Carabelli)"}}
+	if !inList(trk, cached) {
+		t.Errorf("unable to find normalized item '%s' in a list %s", trk.String(), cached)
+	}
+}
+
+// TestAuthorizeTimeout
+func TestAuthorizeTimeout(t *testing.T) {
+	timeout := authTimeout
+	authTimeout = 10 * time.Millisecond
+	defer func() { authTimeout = timeout }()
+	err := authorize("Test", "http://example.com/auth", func(r *http.Request) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected authorization timeout, got %v", err)
+	}
+}
+
+// TestFormatTrack
+func TestFormatTrack(t *testing.T) {
+	track := Track{Name: "La cumparsita", Year: "1951", Orchestra: "Anibal Troilo", Genre: "Tango",
+		Extra: map[string]string{"rating": "5", "bpm": "62"}}
+	expect := "{Orchestra:Anibal Troilo Year:1951 Name:La cumparsita Genre:Tango bpm:62 rating:5}"
+	if s := formatTrack(track); s != expect {
+		t.Errorf("wrong format of track %s, expected %s", s, expect)
+	}
+}
+
+// TestYoutubeText
+func TestYoutubeText(t *testing.T) {
+	cases := []struct {
+		text   string
+		limit  int
+		expect string
+	}{
+		{"<b>Poema</b>", 100, "(b)Poema(/b)"},
+		{"Ángel Vargas", 13, "Ángel Vargas"},
+		{"Ángel Vargas", 12, "Ángel Varga"},
+		{"Ángel", 1, ""},
+		{"Raúl Berón", 10, "Raúl Ber"},
+		{"Raúl Berón", 11, "Raúl Beró"},
+	}
+	for _, c := range cases {
+		if text := youtubeText(c.text, c.limit); text != c.expect {
+			t.Errorf("wrong text %q of %q limited to %d bytes, expected %q", text, c.text, c.limit, c.expect)
+		}
+	}
 }
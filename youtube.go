@@ -2,21 +2,21 @@ package main
 
 import (
 	"fmt"
+	"html"
 	"log"
 	"net/http"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/vkuznet/goplaylist/normalize"
 	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
 	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
 )
 
-// helper function to setup youtube client
-func setupYouTubeService(title string, discography *Discography) {
-	if Config.YoutubeSecret == "" {
-		return
-	}
+// helper function to authenticate youtube client via OAuth flow
+func youtubeProvider() (*YoutubeProvider, error) {
 	ctx := context.Background()
 
 	// OAuth2 configuration
@@ -31,132 +31,138 @@ func setupYouTubeService(title string, discography *Discography) {
 		Scopes:      []string{youtube.YoutubeForceSslScope},
 	}
 
-	http.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+	var provider *YoutubeProvider
+	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
+	err := authorize("YouTube", authURL, func(r *http.Request) error {
 		// obtain code from HTTP request
 		code := r.URL.Query().Get("code")
-
-		token, err := config.Exchange(oauth2.NoContext, code)
+		token, err := config.Exchange(ctx, code)
 		if err != nil {
-			log.Fatalf("Unable to retrieve token from web %v", err)
+			return fmt.Errorf("unable to retrieve token from web: %w", err)
 		}
-
 		client := config.Client(ctx, token)
 
 		// Create the YouTube service
 		service, err := youtube.NewService(ctx, option.WithHTTPClient(client))
 		if err != nil {
-			log.Fatalf("Failed to create YouTube client: %v", err)
+			return fmt.Errorf("failed to create YouTube client: %w", err)
 		}
-		log.Println("Youtube client successfully authenticated")
-
-		// obtain orchestra either from title of discography
-		orchestra := getOrchestra(title, discography)
+		provider = &YoutubeProvider{Service: service}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Println("Youtube client successfully authenticated")
+	return provider, nil
+}
 
-		// check if playlist already exist, if not we will create it
-		playlistID, err := getYoutubePlaylistIDByName(service, title)
-		if err != nil {
-			log.Printf("Unable to lookup playlist ID for '%s', error %v", title, err)
-			playlistID = createYoutubePlaylist(service, title)
-		}
+// YoutubeProvider implements Provider interface for YouTube service
+type YoutubeProvider struct {
+	Service *youtube.Service
+}
 
-		// load cache entries for our playlist
-		tracks, err := cache.Load("youtube", title, string(playlistID))
+// Name implements Provider interface
+func (y *YoutubeProvider) Name() string {
+	return "youtube"
+}
 
-		// fetch existing tracks in our playlist
-		//         tracks, err := getYoutubeTracksForPlaylistID(service, playlistID)
-		if err != nil {
-			log.Printf("unable to find tracks for playlist '%s' (%v), error %v", title, playlistID, err)
-		}
+// FindPlaylist implements Provider interface
+func (y *YoutubeProvider) FindPlaylist(title string) (string, error) {
+	return getYoutubePlaylistIDByName(y.Service, title)
+}
 
-		for idx, track := range discography.Tracks {
-			if track.Orchestra != "" {
-				orchestra = track.Orchestra
-			}
-			year := strings.Split(track.Year, "-")[0]
-			query := fmt.Sprintf("%s %s %v", track.Name, orchestra, year)
-			trk := Track{Name: track.Name, Year: year, Orchestra: orchestra, Artist: track.Artist}
-			if inList(trk, tracks) {
-				fmt.Printf("idx: %4d query: %s, already exist in playlist, skipping...\n", idx, query)
-			} else {
-				fmt.Printf("idx: %4d track: %s\n", idx, query)
-				if err := addToYoutubePlaylist(service, playlistID, query); err == nil {
-					// add track to local cache if was successfully added to playlist
-					cache.AddTrack(title, playlistID, trk)
-				}
-			}
-		}
-		purl := constructYouTubePlaylistURL(playlistID)
-		msg := fmt.Sprintf("New playlist <a href=\"%s\">%s</a> is created", purl, title)
-		log.Println(msg)
-		w.Header().Set("Content-Type", "text/html")
-		w.Write([]byte(msg))
-	})
+// CreatePlaylist implements Provider interface
+func (y *YoutubeProvider) CreatePlaylist(title, description, privacy string) (string, error) {
+	return createYoutubePlaylist(y.Service, title, description, privacy)
+}
 
-	// Start a web server to complete the auth flow
-	go func() {
-		log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", Config.CallbackPort), nil))
-	}()
+// Query implements Provider interface
+func (y *YoutubeProvider) Query(track Track) string {
+	orchestra := normalize.DefaultResolver.Search(track.Orchestra, "youtube")
+	return fmt.Sprintf("%s %s %v", track.Name, orchestra, track.Year)
+}
 
-	// Obtain a token (this part typically includes a user authentication process)
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	fmt.Printf("Go to the following link in your browser then type the "+
-		"authorization code: \n%v\n", authURL)
+// Search implements Provider interface
+func (y *YoutubeProvider) Search(query string, track Track) (Match, error) {
+	searchResp, err := y.Service.Search.List([]string{"id", "snippet"}).
+		Q(query).
+		MaxResults(1).
+		Type("video").
+		Do()
+	if err != nil {
+		return Match{}, err
+	}
+	if len(searchResp.Items) == 0 {
+		return Match{}, fmt.Errorf("%w for query: %s", errNotFound, query)
+	}
+	item := searchResp.Items[0]
+	match := Match{ID: item.Id.VideoId}
+	if item.Snippet != nil {
+		// titles are HTML escaped, e.g. D&#39;Arienzo, and artist is often
+		// known from channel title only, e.g. "Juan D'Arienzo - Topic"
+		match.Title = html.UnescapeString(item.Snippet.Title)
+		match.Score = matchScore(track, match.Title+" "+html.UnescapeString(item.Snippet.ChannelTitle))
+	}
+	return match, nil
+}
 
-	var code string
-	if _, err := fmt.Scan(&code); err != nil {
-		log.Fatalf("Unable to read authorization code %v", err)
+// AddItem implements Provider interface
+func (y *YoutubeProvider) AddItem(playlistID, videoID string, track Track) error {
+	playlistItem := &youtube.PlaylistItem{
+		Snippet: &youtube.PlaylistItemSnippet{
+			PlaylistId: playlistID,
+			ResourceId: &youtube.ResourceId{
+				Kind:    "youtube#video",
+				VideoId: videoID,
+			},
+		},
+		ContentDetails: &youtube.PlaylistItemContentDetails{
+			Note: youtubeText(trackNote(track), 280),
+		},
 	}
+	_, err := y.Service.PlaylistItems.Insert([]string{"snippet", "contentDetails"}, playlistItem).Do()
+	return err
+}
 
+// PlaylistURL implements Provider interface
+func (y *YoutubeProvider) PlaylistURL(playlistID string) string {
+	return constructYouTubePlaylistURL(playlistID)
 }
 
 // helper function to create youtube playlist
-func createYoutubePlaylist(service *youtube.Service, title string) string {
+func createYoutubePlaylist(service *youtube.Service, title, description, privacy string) (string, error) {
 	playlist := &youtube.Playlist{
 		Snippet: &youtube.PlaylistSnippet{
 			Title:       title,
-			Description: "Playlist",
+			Description: youtubeText(description, 5000),
 		},
 		Status: &youtube.PlaylistStatus{
-			PrivacyStatus: "public",
+			PrivacyStatus: privacy,
 		},
 	}
 
 	snippet := []string{"snippet", "status"}
 	createdPlaylist, err := service.Playlists.Insert(snippet, playlist).Do()
 	if err != nil {
-		log.Fatalf("Error creating YouTube playlist: %v", err)
+		return "", fmt.Errorf("error creating YouTube playlist: %w", err)
 	}
-	return createdPlaylist.Id
+	return createdPlaylist.Id, nil
 }
 
-// helper function to add new track to youtube playlist
-func addToYoutubePlaylist(service *youtube.Service, playlistID, query string) error {
-	searchResp, err := service.Search.List([]string{"id"}).
-		Q(query).
-		MaxResults(1).
-		Type("video").
-		Do()
-	if err != nil || len(searchResp.Items) == 0 {
-		log.Printf("Error finding video: %v", err)
-		return err
-	}
-	videoID := searchResp.Items[0].Id.VideoId
-
-	playlistItem := &youtube.PlaylistItem{
-		Snippet: &youtube.PlaylistItemSnippet{
-			PlaylistId: playlistID,
-			ResourceId: &youtube.ResourceId{
-				Kind:    "youtube#video",
-				VideoId: videoID,
-			},
-		},
+// helper function to prepare text for youtube, it does not allow angle
+// brackets in descriptions and limits their length (in bytes)
+func youtubeText(text string, limit int) string {
+	text = strings.NewReplacer("<", "(", ">", ")").Replace(text)
+	if len(text) <= limit {
+		return text
 	}
-	_, err = service.PlaylistItems.Insert([]string{"snippet"}, playlistItem).Do()
-	if err != nil {
-		log.Printf("Error adding video to playlist: %v", err)
-		return err
+	// cut the text at the start of the rune which does not fit
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
 	}
-	return nil
+	return text[:cut]
 }
 
 // helper function to construct youtube playlist URL from given playlist ID